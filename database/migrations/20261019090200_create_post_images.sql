@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE post_images (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    url VARCHAR(500) NOT NULL,
+    caption TEXT NOT NULL DEFAULT '',
+    alt_text TEXT NOT NULL DEFAULT '',
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX post_images_post_id_position_idx ON post_images (post_id, position);
+
+-- +goose Down
+DROP TABLE IF EXISTS post_images;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE post_attachments (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    url VARCHAR(500) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX post_attachments_post_id_position_idx ON post_attachments (post_id, position);
+
+-- +goose Down
+DROP TABLE IF EXISTS post_attachments;
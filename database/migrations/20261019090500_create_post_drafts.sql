@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE post_drafts (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+    title TEXT NOT NULL DEFAULT '',
+    tagline TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One autosave per admin per post; post_id is NULL for a post not yet created.
+CREATE UNIQUE INDEX post_drafts_admin_post_idx ON post_drafts (admin_id, (COALESCE(post_id, 0)));
+
+-- +goose Down
+DROP TABLE IF EXISTS post_drafts;
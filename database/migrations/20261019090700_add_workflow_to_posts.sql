@@ -0,0 +1,28 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'draft'
+    CHECK (status IN ('draft', 'in_review', 'approved', 'scheduled', 'published'));
+ALTER TABLE posts ADD COLUMN publish_at TIMESTAMPTZ;
+ALTER TABLE posts ADD COLUMN reviewer_id INTEGER REFERENCES admins(id) ON DELETE SET NULL;
+UPDATE posts SET status = 'published' WHERE published = TRUE;
+ALTER TABLE posts DROP COLUMN published;
+CREATE INDEX posts_status_idx ON posts (status);
+
+CREATE TABLE post_review_comments (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    admin_id INTEGER REFERENCES admins(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX post_review_comments_post_id_idx ON post_review_comments (post_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS post_review_comments;
+ALTER TABLE posts ADD COLUMN published BOOLEAN DEFAULT FALSE;
+UPDATE posts SET published = TRUE
+    WHERE status = 'published' OR (status = 'scheduled' AND publish_at <= NOW());
+DROP INDEX IF EXISTS posts_status_idx;
+ALTER TABLE posts DROP COLUMN reviewer_id;
+ALTER TABLE posts DROP COLUMN publish_at;
+ALTER TABLE posts DROP COLUMN status;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE admins ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'author'
+    CHECK (role IN ('author', 'editor', 'admin'));
+-- Everyone who could sign in before roles existed had full access.
+UPDATE admins SET role = 'admin';
+ALTER TABLE admins ADD COLUMN name VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE admins ADD COLUMN slug VARCHAR(255) UNIQUE;
+ALTER TABLE admins ADD COLUMN bio TEXT NOT NULL DEFAULT '';
+ALTER TABLE admins ADD COLUMN avatar_url VARCHAR(255) NOT NULL DEFAULT '';
+
+ALTER TABLE posts ADD COLUMN author_id INTEGER REFERENCES admins(id) ON DELETE SET NULL;
+CREATE INDEX posts_author_id_idx ON posts (author_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS posts_author_id_idx;
+ALTER TABLE posts DROP COLUMN author_id;
+ALTER TABLE admins DROP COLUMN avatar_url;
+ALTER TABLE admins DROP COLUMN bio;
+ALTER TABLE admins DROP COLUMN slug;
+ALTER TABLE admins DROP COLUMN name;
+ALTER TABLE admins DROP COLUMN role;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE admins ADD COLUMN disabled_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE admins DROP COLUMN disabled_at;
@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE login_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    ip VARCHAR(64) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    succeeded BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX login_attempts_ip_idx ON login_attempts (ip, created_at);
+CREATE INDEX login_attempts_email_idx ON login_attempts (email, created_at);
+
+CREATE TABLE lockout_events (
+    id SERIAL PRIMARY KEY,
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('account', 'ip')),
+    ip VARCHAR(64) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    failures INTEGER NOT NULL,
+    locked_until TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX lockout_events_created_at_idx ON lockout_events (created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS lockout_events;
+DROP TABLE IF EXISTS login_attempts;
@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE admins ADD COLUMN totp_secret TEXT;
+ALTER TABLE admins ADD COLUMN totp_enabled_at TIMESTAMPTZ;
+ALTER TABLE admins ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE admin_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX admin_recovery_codes_admin_id_idx ON admin_recovery_codes (admin_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS admin_recovery_codes;
+ALTER TABLE admins DROP COLUMN totp_last_step;
+ALTER TABLE admins DROP COLUMN totp_enabled_at;
+ALTER TABLE admins DROP COLUMN totp_secret;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE sessions (
+    token TEXT PRIMARY KEY,
+    data BYTEA NOT NULL,
+    expiry TIMESTAMPTZ NOT NULL,
+    admin_id INTEGER,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX sessions_expiry_idx ON sessions (expiry);
+CREATE INDEX sessions_admin_id_idx ON sessions (admin_id) WHERE admin_id IS NOT NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE password_resets (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX password_resets_admin_id_idx ON password_resets (admin_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS password_resets;
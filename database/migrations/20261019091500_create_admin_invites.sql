@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE admin_invites (
+    id SERIAL PRIMARY KEY,
+    email TEXT NOT NULL DEFAULT '',
+    role TEXT NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    invited_by INTEGER REFERENCES admins(id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    accepted_admin_id INTEGER REFERENCES admins(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS admin_invites;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Tags are stored as slugs, so they double as URL segments.
+CREATE TABLE post_tags (
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (post_id, tag)
+);
+
+CREATE INDEX post_tags_tag_idx ON post_tags (tag);
+
+-- +goose Down
+DROP TABLE IF EXISTS post_tags;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Emails are looked up case-insensitively, so they must also be unique that way.
+CREATE UNIQUE INDEX admins_email_lower_key ON admins (lower(email));
+
+-- +goose Down
+DROP INDEX IF EXISTS admins_email_lower_key;
@@ -0,0 +1,133 @@
+package diff
+
+import "strings"
+
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// Line is one line of a line-based diff.
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Kind names the operation for use as a CSS class.
+func (l Line) Kind() string {
+	switch l.Op {
+	case Insert:
+		return "insert"
+	case Delete:
+		return "delete"
+	}
+	return "equal"
+}
+
+// Prefix is the unified-diff marker for the line.
+func (l Line) Prefix() string {
+	switch l.Op {
+	case Insert:
+		return "+"
+	case Delete:
+		return "-"
+	}
+	return " "
+}
+
+// maxCells bounds the LCS table. Past it the changed middle of the texts is
+// shown as a plain delete-then-insert instead of a minimal diff.
+const maxCells = 4_000_000
+
+// Lines diffs a against b line by line, returning the lines of a that were
+// removed (Delete), the lines of b that were added (Insert) and the lines
+// they share (Equal), in order.
+func Lines(a, b string) []Line {
+	x := splitLines(a)
+	y := splitLines(b)
+
+	// Common prefix and suffix are trimmed first; edits are usually local,
+	// which keeps the LCS table small.
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix &&
+		x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	var out []Line
+	for _, t := range x[:prefix] {
+		out = append(out, Line{Equal, t})
+	}
+	out = append(out, middle(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])...)
+	for _, t := range x[len(x)-suffix:] {
+		out = append(out, Line{Equal, t})
+	}
+	return out
+}
+
+func middle(x, y []string) []Line {
+	var out []Line
+	if len(x)*len(y) > maxCells {
+		for _, t := range x {
+			out = append(out, Line{Delete, t})
+		}
+		for _, t := range y {
+			out = append(out, Line{Insert, t})
+		}
+		return out
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			out = append(out, Line{Equal, x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, Line{Delete, x[i]})
+			i++
+		default:
+			out = append(out, Line{Insert, y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		out = append(out, Line{Delete, x[i]})
+	}
+	for ; j < len(y); j++ {
+		out = append(out, Line{Insert, y[j]})
+	}
+	return out
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,70 @@
+package feed
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+// RSS is an RSS 2.0 document. Only the elements the site emits are modelled.
+type RSS struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel Channel  `xml:"channel"`
+}
+
+type Channel struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Items       []Item `xml:"item"`
+}
+
+type Item struct {
+	Title       string     `xml:"title"`
+	Link        string     `xml:"link"`
+	GUID        string     `xml:"guid"`
+	PubDate     string     `xml:"pubDate"`
+	Description string     `xml:"description,omitempty"`
+	Enclosure   *Enclosure `xml:"enclosure,omitempty"`
+}
+
+// Enclosure points podcast apps and feed readers at a downloadable media
+// file. RSS requires all three attributes.
+type Enclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// PickEnclosure chooses the attachment to expose as an item's enclosure.
+// RSS allows one enclosure per item, so audio is preferred (that is what
+// podcast apps play), falling back to the first attachment.
+func PickEnclosure(attachments []models.Attachment) (models.Attachment, bool) {
+	for _, a := range attachments {
+		if a.IsAudio() {
+			return a, true
+		}
+	}
+	if len(attachments) > 0 {
+		return attachments[0], true
+	}
+	return models.Attachment{}, false
+}
+
+func Write(w io.Writer, rss RSS) error {
+	if rss.Version == "" {
+		rss.Version = "2.0"
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("could not write feed: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(rss); err != nil {
+		return fmt.Errorf("could not encode feed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package feed
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+func TestPickEnclosure_PrefersAudio(t *testing.T) {
+	attachments := []models.Attachment{
+		{ID: 1, ContentType: "application/pdf"},
+		{ID: 2, ContentType: "audio/mpeg"},
+	}
+
+	got, ok := PickEnclosure(attachments)
+	if !ok || got.ID != 2 {
+		t.Errorf("expected audio attachment 2, got %d (ok=%v)", got.ID, ok)
+	}
+
+	got, ok = PickEnclosure(attachments[:1])
+	if !ok || got.ID != 1 {
+		t.Errorf("expected fallback to first attachment, got %d (ok=%v)", got.ID, ok)
+	}
+
+	if _, ok := PickEnclosure(nil); ok {
+		t.Error("expected no enclosure without attachments")
+	}
+}
+
+func TestWrite_Enclosure(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, RSS{Channel: Channel{
+		Title: "Posts",
+		Items: []Item{{
+			Title:     "Episode 1",
+			Enclosure: &Enclosure{URL: "https://example.com/ep1.mp3", Length: 1234, Type: "audio/mpeg"},
+		}},
+	}})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	want := `<enclosure url="https://example.com/ep1.mp3" length="1234" type="audio/mpeg"></enclosure>`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("expected output to contain %q\ngot: %s", want, buf.String())
+	}
+	if !strings.Contains(buf.String(), `<rss version="2.0">`) {
+		t.Errorf("expected rss version attribute, got: %s", buf.String())
+	}
+}
@@ -1,17 +1,27 @@
 package main
 
 import (
-	"fmt"
+	"errors"
+	"log"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/hiimtaylorjones/hiimtaylor-go/content"
+	"github.com/hiimtaylorjones/hiimtaylor-go/diff"
+	"github.com/hiimtaylorjones/hiimtaylor-go/feed"
+	authmiddleware "github.com/hiimtaylorjones/hiimtaylor-go/middleware"
 	"github.com/hiimtaylorjones/hiimtaylor-go/models"
 	"github.com/hiimtaylorjones/hiimtaylor-go/queries"
 	"github.com/hiimtaylorjones/hiimtaylor-go/slug"
 	"github.com/hiimtaylorjones/hiimtaylor-go/uploads"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/hiimtaylorjones/hiimtaylor-go/validation"
 )
 
 func handleHome(w http.ResponseWriter, r *http.Request) {
@@ -20,7 +30,7 @@ func handleHome(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Could not load page", http.StatusInternalServerError)
 		return
 	}
-	renderTemplate(w, "home", map[string]any{"Content": html})
+	renderTemplate(w, r, http.StatusOK, "home", map[string]any{"Content": html})
 }
 
 func handleListPosts(w http.ResponseWriter, r *http.Request) {
@@ -48,7 +58,7 @@ func handleListPosts(w http.ResponseWriter, r *http.Request) {
 
 	pagination := models.NewPagination(page, perPage, totalCount)
 
-	renderTemplate(w, "posts.index", map[string]any{
+	renderTemplate(w, r, http.StatusOK, "posts.index", map[string]any{
 		"Posts":      posts,
 		"Pagination": pagination,
 	})
@@ -61,25 +71,109 @@ func handleShowPost(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
-	renderTemplate(w, "posts.show", map[string]any{"Post": post})
+	// Posts still in the workflow, or scheduled for later, are only visible
+	// to users who may edit them.
+	if !post.Published() {
+		admin, ok := authmiddleware.SignedInAdmin(r)
+		if !ok || !admin.CanEdit(post) {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	images, err := queries.GetPostImages(post.ID)
+	if err != nil {
+		http.Error(w, "Error fetching gallery", http.StatusInternalServerError)
+		return
+	}
+	attachments, err := queries.GetPostAttachments(post.ID)
+	if err != nil {
+		http.Error(w, "Error fetching attachments", http.StatusInternalServerError)
+		return
+	}
+	var author *models.Admin
+	if post.AuthorID != 0 {
+		if a, err := queries.GetAdminByID(post.AuthorID); err == nil {
+			author = &a
+		}
+	}
+	renderTemplate(w, r, http.StatusOK, postTemplate(post.Layout), map[string]any{
+		"Post":        post,
+		"Author":      author,
+		"Images":      images,
+		"Attachments": attachments,
+	})
+}
+
+// currentAdminID returns the signed-in admin's ID, or 0 when there is none.
+func currentAdminID(r *http.Request) int {
+	id, _ := strconv.Atoi(sessionManager.GetString(r.Context(), "admin_id"))
+	return id
+}
+
+// currentAdmin returns the signed-in user loaded by RequireAdmin. Only
+// protected routes have one.
+func currentAdmin(r *http.Request) models.Admin {
+	admin, _ := authmiddleware.CurrentAdmin(r.Context())
+	return admin
+}
+
+// offerDraft checks for an editor autosave that differs from post. With
+// ?restore=1 the autosave's fields are copied into post so the form shows
+// them; otherwise it is added to data as "Draft" so the editor can offer to
+// restore it. Autosaves older than the saved post are ignored.
+func offerDraft(r *http.Request, post *models.Post, data map[string]any) {
+	draft, err := queries.GetDraft(currentAdminID(r), post.ID)
+	if err != nil {
+		return
+	}
+	if post.ID != 0 && !draft.UpdatedAt.After(post.UpdatedAt) {
+		return
+	}
+	if draft.Title == post.Title && draft.Tagline == post.Tagline && draft.Body == post.Body {
+		return
+	}
+
+	if r.URL.Query().Get("restore") == "1" {
+		post.Title, post.Tagline, post.Body = draft.Title, draft.Tagline, draft.Body
+		data["Restored"] = true
+		return
+	}
+	data["Draft"] = draft
 }
 
 func handleNewPost(w http.ResponseWriter, r *http.Request) {
-	renderTemplate(w, "posts.new", nil)
+	var post models.Post
+	data := map[string]any{"Layouts": postLayouts, "EditorPath": "/posts/new"}
+	offerDraft(r, &post, data)
+	data["Post"] = post
+	renderTemplate(w, r, http.StatusOK, "posts.new", data)
 }
 
 func handleCreatePost(w http.ResponseWriter, r *http.Request) {
-	// Caps form data at 10 MB limit.
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	// Holds at most 10 MB of the form in memory.
+	if err := r.ParseMultipartForm(authmiddleware.FormMemoryBytes); err != nil {
 		http.Error(w, "Error parsing form", http.StatusBadRequest)
 		return
 	}
 
-	title := r.FormValue("title")
-	tagline := r.FormValue("tagline")
-	body := r.FormValue("body")
-	published := r.FormValue("published") == "true"
-	postSlug := slug.Generate(title)
+	post := postFromForm(r, models.Post{})
+	errs := validation.Post(post)
+	checkUploads(r, errs)
+	if errs.Any() {
+		renderTemplate(w, r, http.StatusUnprocessableEntity, "posts.new", map[string]any{
+			"Post":       post,
+			"Errors":     errs,
+			"Layouts":    postLayouts,
+			"EditorPath": "/posts/new",
+		})
+		return
+	}
+
+	postSlug, err := slug.Unique(slug.Generate(post.Title), queries.SlugExists)
+	if err != nil {
+		http.Error(w, "Error creating post", http.StatusInternalServerError)
+		return
+	}
 
 	var bannerImageURL string
 	file, header, err := r.FormFile("banner_image")
@@ -92,13 +186,220 @@ func handleCreatePost(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	post, err := queries.CreatePost(title, tagline, body, postSlug, bannerImageURL, published)
+	created, err := queries.CreatePost(post.Title, post.Tagline, post.Body, postSlug, bannerImageURL, post.Metadata, post.Layout, currentAdmin(r).ID)
 	if err != nil {
 		http.Error(w, "Error creating post", http.StatusInternalServerError)
 		return
 	}
 
-	http.Redirect(w, r, "/posts/"+post.Slug, http.StatusSeeOther)
+	if err := saveGallery(r, created.ID); err != nil {
+		http.Error(w, "Error saving gallery", http.StatusInternalServerError)
+		return
+	}
+
+	if err := saveAttachments(r, created.ID); err != nil {
+		http.Error(w, "Error saving attachments", http.StatusInternalServerError)
+		return
+	}
+
+	// The post now holds what was typed; the "new post" autosave is done.
+	if err := queries.DeleteDraft(currentAdminID(r), 0); err != nil {
+		log.Printf("could not clear autosave: %v", err)
+	}
+
+	setFlash(r, "Post created.")
+	http.Redirect(w, r, "/posts/"+created.Slug, http.StatusSeeOther)
+}
+
+// postFromForm returns base with the editable fields replaced by the
+// submitted form values. Uploads are handled separately.
+func postFromForm(r *http.Request, base models.Post) models.Post {
+	post := base
+	post.Title = strings.TrimSpace(r.FormValue("title"))
+	post.Tagline = r.FormValue("tagline")
+	post.Body = r.FormValue("body")
+	post.Metadata = parseMeta(r)
+	post.Layout = parseLayout(r)
+	return post
+}
+
+// parseMeta collects the key/value rows of the metadata editor. The form
+// submits parallel meta_key and meta_value fields; rows with a blank key are
+// dropped so clearing a key removes it.
+func parseMeta(r *http.Request) models.PostMeta {
+	keys := r.Form["meta_key"]
+	values := r.Form["meta_value"]
+
+	meta := models.PostMeta{}
+	for i, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" || i >= len(values) {
+			continue
+		}
+		meta[key] = strings.TrimSpace(values[i])
+	}
+	return meta
+}
+
+// parseLayout returns the submitted layout if it names one of postLayouts,
+// and "" (the default layout) otherwise.
+func parseLayout(r *http.Request) string {
+	layout := r.FormValue("layout")
+	if slices.Contains(postLayouts, layout) {
+		return layout
+	}
+	return ""
+}
+
+// saveGallery applies the gallery section of the post form. Images listed in
+// image_id get their caption, alt text and position updated, or are removed
+// when image_remove_<id> is checked. Files picked in the multi-file
+// gallery_images input are then appended in the order they were selected.
+func saveGallery(r *http.Request, postID int) error {
+	existing, err := queries.GetPostImages(postID)
+	if err != nil {
+		return err
+	}
+	owned := make(map[int]models.PostImage, len(existing))
+	for _, img := range existing {
+		owned[img.ID] = img
+	}
+
+	for _, rawID := range r.Form["image_id"] {
+		id, err := strconv.Atoi(rawID)
+		if err != nil {
+			continue
+		}
+		img, ok := owned[id]
+		if !ok {
+			continue
+		}
+
+		if r.FormValue("image_remove_"+rawID) == "true" {
+			if err := queries.DeletePostImage(img.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		position := img.Position
+		if n, err := strconv.Atoi(r.FormValue("image_position_" + rawID)); err == nil {
+			position = n
+		}
+		caption := strings.TrimSpace(r.FormValue("image_caption_" + rawID))
+		altText := strings.TrimSpace(r.FormValue("image_alt_" + rawID))
+		if err := queries.UpdatePostImage(img.ID, caption, altText, position); err != nil {
+			return err
+		}
+	}
+
+	if r.MultipartForm == nil {
+		return nil
+	}
+	for _, header := range r.MultipartForm.File["gallery_images"] {
+		url, err := uploads.SaveHeader(header)
+		if err != nil {
+			return err
+		}
+		if _, err := queries.CreatePostImage(postID, url, "", ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkUploads adds an error to errs for any file picked in the post form
+// that won't be accepted, so the form is re-rendered before anything is
+// saved rather than failing halfway through.
+func checkUploads(r *http.Request, errs validation.Errors) {
+	checkFiles(r, errs, "banner_image", uploads.CheckImage, imageTypeMessage)
+	checkFiles(r, errs, "gallery_images", uploads.CheckImage, imageTypeMessage)
+	checkFiles(r, errs, "attachments", uploads.CheckAttachment, "isn't a PDF, slide deck or audio file.")
+}
+
+const imageTypeMessage = "isn't a JPEG, PNG, GIF or WebP image."
+
+// checkFiles sets errs[field] to the first file picked in field that check
+// rejects, named and followed by message.
+func checkFiles(r *http.Request, errs validation.Errors, field string, check func(*multipart.FileHeader) error, message string) {
+	if r.MultipartForm == nil {
+		return
+	}
+	for _, header := range r.MultipartForm.File[field] {
+		if err := check(header); err != nil {
+			errs[field] = header.Filename + " " + message
+			return
+		}
+	}
+}
+
+// saveAttachments removes attachments whose attachment_remove_<id> box is
+// checked and appends files picked in the multi-file attachments input.
+func saveAttachments(r *http.Request, postID int) error {
+	existing, err := queries.GetPostAttachments(postID)
+	if err != nil {
+		return err
+	}
+	for _, a := range existing {
+		if r.FormValue("attachment_remove_"+strconv.Itoa(a.ID)) == "true" {
+			if err := queries.DeleteAttachment(a.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if r.MultipartForm == nil {
+		return nil
+	}
+	for _, header := range r.MultipartForm.File["attachments"] {
+		info, err := uploads.SaveWithInfo(header)
+		if err != nil {
+			return err
+		}
+		_, err = queries.CreateAttachment(postID, info.URL, info.Filename, info.ContentType, info.Size)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// editorData loads what the edit form needs besides the post itself.
+func editorData(post models.Post) (map[string]any, error) {
+	images, err := queries.GetPostImages(post.ID)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := queries.GetPostAttachments(post.ID)
+	if err != nil {
+		return nil, err
+	}
+	admins, err := queries.ListAdmins()
+	if err != nil {
+		return nil, err
+	}
+	// Only users who can approve a post are offered as reviewers.
+	var reviewers []models.Admin
+	for _, a := range admins {
+		if a.Can(models.PermPublishPosts) {
+			reviewers = append(reviewers, a)
+		}
+	}
+	comments, err := queries.GetReviewComments(post.ID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"Post":        post,
+		"Layouts":     postLayouts,
+		"Images":      images,
+		"Attachments": attachments,
+		"Reviewers":   reviewers,
+		"Comments":    comments,
+		"EditorPath":  "/posts/" + post.Slug + "/edit",
+	}, nil
 }
 
 func handleEditPost(w http.ResponseWriter, r *http.Request) {
@@ -108,7 +409,14 @@ func handleEditPost(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
-	renderTemplate(w, "posts.edit", map[string]any{"Post": post})
+	data, err := editorData(post)
+	if err != nil {
+		http.Error(w, "Error loading post", http.StatusInternalServerError)
+		return
+	}
+	offerDraft(r, &post, data)
+	data["Post"] = post
+	renderTemplate(w, r, http.StatusOK, "posts.edit", data)
 }
 
 func handleUpdatePost(w http.ResponseWriter, r *http.Request) {
@@ -119,36 +427,109 @@ func handleUpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	if err := r.ParseMultipartForm(authmiddleware.FormMemoryBytes); err != nil {
 		http.Error(w, "Error parsing form", http.StatusBadRequest)
 		return
 	}
 
-	title := r.FormValue("title")
-	tagline := r.FormValue("tagline")
-	body := r.FormValue("body")
-	published := r.FormValue("published") == "true"
-	bannerImageURL := post.BannerImageURL
+	mine := postFromForm(r, post)
+	errs := validation.Post(mine)
+	checkUploads(r, errs)
+	if errs.Any() {
+		data, err := editorData(mine)
+		if err != nil {
+			http.Error(w, "Error loading post", http.StatusInternalServerError)
+			return
+		}
+		// Keep the version the author started from so a concurrent save is
+		// still caught when they fix the errors and resubmit.
+		data["Post"] = withFormVersion(r, mine)
+		data["Errors"] = errs
+		renderTemplate(w, r, http.StatusUnprocessableEntity, "posts.edit", data)
+		return
+	}
 
+	newBanner := ""
 	file, header, err := r.FormFile("banner_image")
 	if err == nil {
 		defer file.Close()
-		bannerImageURL, err = uploads.Save(file, header)
+		newBanner, err = uploads.Save(file, header)
 		if err != nil {
 			http.Error(w, "Error saving image", http.StatusInternalServerError)
 			return
 		}
+		mine.BannerImageURL = newBanner
 	}
 
-	updated, err := queries.UpdatePost(post.ID, title, tagline, body, bannerImageURL, published)
+	version, _ := strconv.Atoi(r.FormValue("version"))
+	updated, err := queries.UpdatePost(post.ID, version, mine.Title, mine.Tagline, mine.Body, mine.BannerImageURL, mine.Metadata, mine.Layout)
+	if err != nil && newBanner != "" {
+		// The post still points at its old banner, so the new one would be
+		// left behind unused.
+		if err := uploads.Remove(newBanner); err != nil {
+			log.Printf("could not remove unused banner: %v", err)
+		}
+		mine.BannerImageURL = post.BannerImageURL
+	}
+	if errors.Is(err, queries.ErrConflict) {
+		renderConflict(w, r, mine)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error updating post", http.StatusInternalServerError)
 		return
 	}
 
+	if err := saveGallery(r, post.ID); err != nil {
+		http.Error(w, "Error saving gallery", http.StatusInternalServerError)
+		return
+	}
+
+	if err := saveAttachments(r, post.ID); err != nil {
+		http.Error(w, "Error saving attachments", http.StatusInternalServerError)
+		return
+	}
+
+	if err := queries.DeleteDraft(currentAdminID(r), post.ID); err != nil {
+		log.Printf("could not clear autosave: %v", err)
+	}
+
+	setFlash(r, "Post updated.")
 	http.Redirect(w, r, "/posts/"+updated.Slug, http.StatusSeeOther)
 }
 
+// withFormVersion returns post carrying the version submitted with the form.
+func withFormVersion(r *http.Request, post models.Post) models.Post {
+	if v, err := strconv.Atoi(r.FormValue("version")); err == nil {
+		post.Version = v
+	}
+	return post
+}
+
+// renderConflict re-renders the edit form after UpdatePost reported a
+// conflict. The form keeps the author's submitted values (mine) but carries
+// the latest version, so saving again deliberately overwrites; the latest
+// saved post and a diff from it to mine are shown alongside.
+func renderConflict(w http.ResponseWriter, r *http.Request, mine models.Post) {
+	theirs, err := queries.GetPostByID(mine.ID)
+	if err != nil {
+		http.Error(w, "Error updating post", http.StatusInternalServerError)
+		return
+	}
+	mine.Version = theirs.Version
+
+	data, err := editorData(mine)
+	if err != nil {
+		http.Error(w, "Error loading post", http.StatusInternalServerError)
+		return
+	}
+	data["Conflict"] = map[string]any{
+		"Theirs":   theirs,
+		"BodyDiff": diff.Lines(theirs.Body, mine.Body),
+	}
+	renderTemplate(w, r, http.StatusConflict, "posts.edit", data)
+}
+
 func handleDeletePost(w http.ResponseWriter, r *http.Request) {
 	postSlug := chi.URLParam(r, "slug")
 	post, err := queries.GetPostBySlug(postSlug)
@@ -157,52 +538,150 @@ func handleDeletePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := queries.DeletePost(post.ID); err != nil {
+	if err := queries.TrashPost(post.ID); err != nil {
 		http.Error(w, "Error deleting post", http.StatusInternalServerError)
 		return
 	}
 
-	http.Redirect(w, r, "/posts", http.StatusSeeOther)
+	setFlash(r, "Post moved to trash.")
+	// Only editors can see the trash; authors go back to the dashboard.
+	fallback := "/admin"
+	if currentAdmin(r).Can(models.PermEditAnyPost) {
+		fallback = "/admin/trash"
+	}
+	redirectBack(w, r, fallback)
 }
 
-func handleResume(w http.ResponseWriter, r *http.Request) {
-	html, err := content.Render("resume.md")
-	if err != nil {
-		http.Error(w, "Could not load page", http.StatusInternalServerError)
-		return
+// siteURL is the absolute origin used in feeds. SITE_URL wins when set so
+// links stay stable behind proxies; otherwise the request host is used.
+func siteURL(r *http.Request) string {
+	if u := os.Getenv("SITE_URL"); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
 	}
-	renderTemplate(w, "resume", map[string]any{"Content": html})
+	return scheme + "://" + r.Host
 }
 
-func handleLoginForm(w http.ResponseWriter, r *http.Request) {
-	renderTemplate(w, "login", nil)
+// errNoSiteURL is returned when a link has to be emailed but SITE_URL isn't
+// set.
+var errNoSiteURL = errors.New("SITE_URL must be set to email links")
+
+// errNoMailer is returned when a link would be emailed but no mail server is
+// configured. Links are never only logged, since the log would then hold
+// live tokens.
+var errNoMailer = errors.New("SMTP_HOST must be set to email links")
+
+// emailLinkBase is the origin for links sent by email. Unlike siteURL it
+// never falls back to the request host: the Host header is whatever the
+// sender chose, so a reset link built from it could point at their server
+// and hand them the token.
+func emailLinkBase() (string, error) {
+	u := os.Getenv("SITE_URL")
+	if u == "" {
+		return "", errNoSiteURL
+	}
+	return strings.TrimSuffix(u, "/"), nil
 }
 
-func handleLogin(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Error parsing form", http.StatusBadRequest)
+// feedSize is how many of the newest posts a feed lists.
+const feedSize = 20
+
+func handleFeed(w http.ResponseWriter, r *http.Request) {
+	posts, err := queries.GetPublishedPosts(1, feedSize)
+	if err != nil {
+		http.Error(w, "Error fetching posts", http.StatusInternalServerError)
 		return
 	}
+	writeFeed(w, r, feed.Channel{
+		Title:       "hiimtaylorjones",
+		Link:        siteURL(r) + "/posts",
+		Description: "Posts from hiimtaylorjones",
+	}, posts)
+}
 
-	email := r.FormValue("email")
-	password := r.FormValue("password")
+// handleTagFeed serves the posts with one tag. Posts carrying audio get an
+// enclosure, so a tag used for episodes works as a podcast feed.
+func handleTagFeed(w http.ResponseWriter, r *http.Request) {
+	tag := chi.URLParam(r, "tag")
+	if tag != slug.Generate(tag) {
+		http.NotFound(w, r)
+		return
+	}
+	posts, err := queries.GetPublishedPostsByTag(tag, 1, feedSize)
+	if err != nil {
+		http.Error(w, "Error fetching posts", http.StatusInternalServerError)
+		return
+	}
+	writeFeed(w, r, feed.Channel{
+		Title:       "hiimtaylorjones: " + tag,
+		Link:        siteURL(r) + "/posts",
+		Description: "Posts tagged " + tag + " from hiimtaylorjones",
+	}, posts)
+}
 
-	admin, err := queries.GetAdminByEmail(email)
+// writeFeed writes posts as RSS items in channel, each with its first
+// suitable attachment as the enclosure.
+func writeFeed(w http.ResponseWriter, r *http.Request, channel feed.Channel, posts []models.Post) {
+	ids := make([]int, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	attachments, err := queries.GetAttachmentsForPosts(ids)
 	if err != nil {
-		renderTemplate(w, "login", map[string]any{"Error": "Invalid email or password"})
+		http.Error(w, "Error fetching attachments", http.StatusInternalServerError)
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(admin.EncryptedPassword), []byte(password)); err != nil {
-		renderTemplate(w, "login", map[string]any{"Error": "Invalid email or password"})
+	base := siteURL(r)
+	rss := feed.RSS{Channel: channel}
+	for _, p := range posts {
+		link := base + "/posts/" + p.Slug
+		item := feed.Item{
+			Title:       p.Title,
+			Link:        link,
+			GUID:        link,
+			PubDate:     p.PublishedAt().Format(time.RFC1123Z),
+			Description: p.Tagline,
+		}
+		if a, ok := feed.PickEnclosure(attachments[p.ID]); ok {
+			item.Enclosure = &feed.Enclosure{URL: base + a.URL, Length: a.SizeBytes, Type: a.ContentType}
+		}
+		rss.Channel.Items = append(rss.Channel.Items, item)
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	if err := feed.Write(w, rss); err != nil {
+		http.Error(w, "Error rendering feed", http.StatusInternalServerError)
+	}
+}
+
+func handleResume(w http.ResponseWriter, r *http.Request) {
+	html, err := content.Render("resume.md")
+	if err != nil {
+		http.Error(w, "Could not load page", http.StatusInternalServerError)
 		return
 	}
+	renderTemplate(w, r, http.StatusOK, "resume", map[string]any{"Content": html})
+}
 
-	sessionManager.Put(r.Context(), "admin_id", fmt.Sprintf("%d", admin.ID))
-	http.Redirect(w, r, "/", http.StatusSeeOther)
+// handleCSRFFailure explains a rejected form submission. It links back to
+// the page the form was on when that page is on this site.
+func handleCSRFFailure(w http.ResponseWriter, r *http.Request) {
+	var back string
+	if ref, err := url.Parse(r.Referer()); err == nil && ref.Host == r.Host && ref.Path != "" {
+		back = ref.RequestURI()
+	}
+	renderTemplate(w, r, http.StatusForbidden, "errors.csrf", map[string]any{"Back": back})
 }
 
 func handleLogout(w http.ResponseWriter, r *http.Request) {
-	sessionManager.Remove(r.Context(), "admin_id")
+	if err := signOut(r); err != nil {
+		http.Error(w, "Error signing out", http.StatusInternalServerError)
+		return
+	}
+	setFlash(r, "Signed out.")
 	http.Redirect(w, r, "/login", http.StatusSeeOther)
 }
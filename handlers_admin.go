@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/hiimtaylorjones/hiimtaylor-go/queries"
+	"github.com/hiimtaylorjones/hiimtaylor-go/slug"
+	"github.com/hiimtaylorjones/hiimtaylor-go/uploads"
+)
+
+// idParam parses the {id} URL parameter used by admin routes.
+func idParam(r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id < 1 {
+		return 0, false
+	}
+	return id, true
+}
+
+// redirectBack sends the admin back to the page named by the return_to form
+// field, so quick actions keep the dashboard's filters. Only local /admin
+// paths are honoured; anything else falls back to fallback.
+func redirectBack(w http.ResponseWriter, r *http.Request, fallback string) {
+	target := r.FormValue("return_to")
+	if !strings.HasPrefix(target, "/admin") || strings.HasPrefix(target, "//") {
+		target = fallback
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// dashboardURL returns /admin with params, overriding the given keys.
+func dashboardURL(params url.Values, overrides ...string) string {
+	q := url.Values{}
+	for k, v := range params {
+		q[k] = v
+	}
+	for i := 0; i+1 < len(overrides); i += 2 {
+		q.Set(overrides[i], overrides[i+1])
+	}
+	if len(q) == 0 {
+		return "/admin"
+	}
+	return "/admin?" + q.Encode()
+}
+
+func handleDashboard(w http.ResponseWriter, r *http.Request) {
+	const perPage = 20
+
+	params := r.URL.Query()
+	filter := queries.PostFilter{
+		Status: models.PostStatus(params.Get("status")),
+		Search: strings.TrimSpace(params.Get("q")),
+		Sort:   params.Get("sort"),
+		Desc:   params.Get("dir") == "desc",
+	}
+	if params.Get("waiting") == "me" {
+		filter.WaitingOn = currentAdminID(r)
+	}
+	if params.Get("author") == "me" {
+		filter.AuthorID = currentAdminID(r)
+	}
+	if t, err := time.Parse("2006-01-02", params.Get("from")); err == nil {
+		filter.From = t
+	}
+	if t, err := time.Parse("2006-01-02", params.Get("to")); err == nil {
+		// "to" is inclusive of the whole day.
+		filter.To = t.AddDate(0, 0, 1)
+	}
+
+	page := 1
+	if n, err := strconv.Atoi(params.Get("page")); err == nil && n > 0 {
+		page = n
+	}
+
+	totalCount, err := queries.CountPosts(filter)
+	if err != nil {
+		http.Error(w, "Error fetching posts", http.StatusInternalServerError)
+		return
+	}
+	posts, err := queries.ListPosts(filter, page, perPage)
+	if err != nil {
+		http.Error(w, "Error fetching posts", http.StatusInternalServerError)
+		return
+	}
+	ids := make([]int, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	tags, err := queries.GetTagsForPosts(ids)
+	if err != nil {
+		http.Error(w, "Error fetching posts", http.StatusInternalServerError)
+		return
+	}
+	pagination := models.NewPagination(page, perPage, totalCount)
+
+	// Clicking a column header sorts by it, toggling direction when it is
+	// already the active sort.
+	sortURLs := map[string]string{}
+	for _, col := range []string{"title", "status", "created", "updated"} {
+		dir := "asc"
+		if filter.Sort == col && !filter.Desc {
+			dir = "desc"
+		}
+		sortURLs[col] = dashboardURL(params, "sort", col, "dir", dir, "page", "1")
+	}
+
+	renderTemplate(w, r, http.StatusOK, "admin.dashboard", map[string]any{
+		"Posts":      posts,
+		"Tags":       tags,
+		"Pagination": pagination,
+		"Params":     params,
+		"Statuses":   models.Statuses,
+		"WaitingURL": dashboardURL(url.Values{}, "waiting", "me"),
+		"MineURL":    dashboardURL(url.Values{}, "author", "me"),
+		"SortURLs":   sortURLs,
+		"PrevURL":    dashboardURL(params, "page", strconv.Itoa(page-1)),
+		"NextURL":    dashboardURL(params, "page", strconv.Itoa(page+1)),
+		"ReturnTo":   r.URL.RequestURI(),
+	})
+}
+
+func handleBulkPosts(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	var ids []int
+	for _, raw := range r.Form["post_id"] {
+		if id, err := strconv.Atoi(raw); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	action := r.FormValue("action")
+	var (
+		changed int64
+		err     error
+		verb    string
+	)
+	switch action {
+	case "publish":
+		changed, err = queries.BulkTransition(ids, models.StatusPublished)
+		verb = "published"
+	case "draft":
+		changed, err = queries.BulkTransition(ids, models.StatusDraft)
+		verb = "moved back to draft"
+	case "delete":
+		changed, err = queries.BulkTrashPosts(ids)
+		verb = "moved to the trash"
+	case "tag", "untag":
+		tag := slug.Generate(r.FormValue("tag"))
+		if tag == "" {
+			http.Error(w, "Enter a tag to add or remove.", http.StatusUnprocessableEntity)
+			return
+		}
+		if action == "tag" {
+			changed, err = queries.BulkTagPosts(ids, tag)
+			verb = "tagged " + tag
+		} else {
+			changed, err = queries.BulkUntagPosts(ids, tag)
+			verb = "untagged " + tag
+		}
+	default:
+		http.Error(w, "Unknown bulk action", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating posts", http.StatusInternalServerError)
+		return
+	}
+
+	returnTo := r.FormValue("return_to")
+	if !strings.HasPrefix(returnTo, "/admin") || strings.HasPrefix(returnTo, "//") {
+		returnTo = "/admin"
+	}
+	renderTemplate(w, r, http.StatusOK, "admin.bulk", map[string]any{
+		"Changed":  changed,
+		"Selected": len(ids),
+		"Verb":     verb,
+		"ReturnTo": returnTo,
+	})
+}
+
+func handleDuplicatePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	post, err := queries.GetPostByID(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	newSlug, err := slug.Unique(post.Slug+"-copy", queries.SlugExists)
+	if err != nil {
+		http.Error(w, "Error duplicating post", http.StatusInternalServerError)
+		return
+	}
+	duplicate, err := queries.DuplicatePost(post.ID, newSlug, currentAdmin(r).ID)
+	if err != nil {
+		http.Error(w, "Error duplicating post", http.StatusInternalServerError)
+		return
+	}
+
+	setFlash(r, "Post duplicated. You are editing the new draft.")
+	http.Redirect(w, r, "/posts/"+duplicate.Slug+"/edit", http.StatusSeeOther)
+}
+
+// handlePreview renders Markdown for the editor's live preview pane, using
+// the same pipeline as Post.RenderedBody so the preview matches the post.
+func handlePreview(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	post := models.Post{Body: r.FormValue("body")}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(post.RenderedBody()))
+}
+
+type autosaveRequest struct {
+	PostID  int    `json:"post_id"`
+	Title   string `json:"title"`
+	Tagline string `json:"tagline"`
+	Body    string `json:"body"`
+}
+
+// handleAutosave stores the editor's current state in post_drafts. It never
+// touches the post itself.
+func handleAutosave(w http.ResponseWriter, r *http.Request) {
+	var req autosaveRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "Invalid autosave", http.StatusBadRequest)
+		return
+	}
+	if req.PostID != 0 {
+		post, err := queries.GetPostByID(req.PostID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if !currentAdmin(r).CanEdit(post) {
+			http.Error(w, "You can only change your own posts.", http.StatusForbidden)
+			return
+		}
+	}
+
+	draft, err := queries.SaveDraft(currentAdminID(r), req.PostID, req.Title, req.Tagline, req.Body)
+	if err != nil {
+		http.Error(w, "Error saving draft", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"saved_at": draft.UpdatedAt})
+}
+
+func handleDiscardAutosave(w http.ResponseWriter, r *http.Request) {
+	postID, _ := strconv.Atoi(r.FormValue("post_id"))
+	if err := queries.DeleteDraft(currentAdminID(r), postID); err != nil {
+		http.Error(w, "Error discarding draft", http.StatusInternalServerError)
+		return
+	}
+
+	setFlash(r, "Unsaved changes discarded.")
+	target := r.FormValue("return_to")
+	if !strings.HasPrefix(target, "/posts/") {
+		target = "/admin"
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+func handleTrash(w http.ResponseWriter, r *http.Request) {
+	posts, err := queries.GetTrashedPosts()
+	if err != nil {
+		http.Error(w, "Error fetching trash", http.StatusInternalServerError)
+		return
+	}
+	renderTemplate(w, r, http.StatusOK, "admin.trash", map[string]any{
+		"Posts":         posts,
+		"RetentionDays": int(trashRetention.Hours() / 24),
+	})
+}
+
+func handleRestorePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if err := queries.RestorePost(id); err != nil {
+		http.Error(w, "Error restoring post", http.StatusInternalServerError)
+		return
+	}
+	setFlash(r, "Post restored.")
+	http.Redirect(w, r, "/admin/trash", http.StatusSeeOther)
+}
+
+func handlePurgePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	files, err := queries.PurgePost(id)
+	if err != nil {
+		http.Error(w, "Error deleting post", http.StatusInternalServerError)
+		return
+	}
+	removeUploads(files)
+	setFlash(r, "Post permanently deleted.")
+	http.Redirect(w, r, "/admin/trash", http.StatusSeeOther)
+}
+
+// removeUploads deletes the files left over by purged posts. The posts are
+// already gone, so a file that can't be removed is only logged.
+func removeUploads(urls []string) {
+	for _, url := range urls {
+		if !strings.HasPrefix(url, "/static/uploads/") {
+			continue
+		}
+		if err := uploads.Remove(url); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("could not remove purged post's file: %v", err)
+		}
+	}
+}
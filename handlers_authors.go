@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	authmiddleware "github.com/hiimtaylorjones/hiimtaylor-go/middleware"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/hiimtaylorjones/hiimtaylor-go/queries"
+	"github.com/hiimtaylorjones/hiimtaylor-go/slug"
+	"github.com/hiimtaylorjones/hiimtaylor-go/uploads"
+	"github.com/hiimtaylorjones/hiimtaylor-go/validation"
+)
+
+// handleShowAuthor renders an author's public page: their profile and their
+// published posts.
+func handleShowAuthor(w http.ResponseWriter, r *http.Request) {
+	const perPage = 10
+
+	author, err := queries.GetAuthorBySlug(chi.URLParam(r, "slug"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	page := 1
+	if n, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && n > 0 {
+		page = n
+	}
+
+	totalCount, err := queries.CountPublishedPostsByAuthor(author.ID)
+	if err != nil {
+		http.Error(w, "Error fetching posts", http.StatusInternalServerError)
+		return
+	}
+	posts, err := queries.GetPublishedPostsByAuthor(author.ID, page, perPage)
+	if err != nil {
+		http.Error(w, "Error fetching posts", http.StatusInternalServerError)
+		return
+	}
+
+	renderTemplate(w, r, http.StatusOK, "authors.show", map[string]any{
+		"Author":     author,
+		"Posts":      posts,
+		"Pagination": models.NewPagination(page, perPage, totalCount),
+	})
+}
+
+func handleProfile(w http.ResponseWriter, r *http.Request) {
+	renderTemplate(w, r, http.StatusOK, "admin.profile", map[string]any{
+		"Profile": currentAdmin(r),
+	})
+}
+
+// handleUpdateProfile saves the signed-in user's author profile. Leaving the
+// page address blank derives one from the name.
+func handleUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(authmiddleware.FormMemoryBytes); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	profile := currentAdmin(r)
+	profile.Name = strings.TrimSpace(r.FormValue("name"))
+	profile.Slug = strings.TrimSpace(r.FormValue("slug"))
+	profile.Bio = strings.TrimSpace(r.FormValue("bio"))
+
+	errs := validation.Profile(profile)
+	checkFiles(r, errs, "avatar", uploads.CheckImage, imageTypeMessage)
+	if !errs.Any() {
+		if profile.Slug == "" && profile.Name != "" {
+			taken := func(s string) (bool, error) { return queries.AuthorSlugTaken(s, profile.ID) }
+			generated, err := slug.Unique(slug.Generate(profile.Name), taken)
+			if err != nil {
+				http.Error(w, "Error saving profile", http.StatusInternalServerError)
+				return
+			}
+			profile.Slug = generated
+		} else if profile.Slug != "" {
+			taken, err := queries.AuthorSlugTaken(profile.Slug, profile.ID)
+			if err != nil {
+				http.Error(w, "Error saving profile", http.StatusInternalServerError)
+				return
+			}
+			if taken {
+				errs["slug"] = "Another author already uses this address."
+			}
+		}
+	}
+	if errs.Any() {
+		renderTemplate(w, r, http.StatusUnprocessableEntity, "admin.profile", map[string]any{
+			"Profile": profile,
+			"Errors":  errs,
+		})
+		return
+	}
+
+	if r.FormValue("avatar_remove") == "true" {
+		profile.AvatarURL = ""
+	}
+	file, header, err := r.FormFile("avatar")
+	if err == nil {
+		defer file.Close()
+		profile.AvatarURL, err = uploads.Save(file, header)
+		if err != nil {
+			http.Error(w, "Error saving image", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if _, err := queries.UpdateProfile(profile.ID, profile.Name, profile.Slug, profile.Bio, profile.AvatarURL); err != nil {
+		http.Error(w, "Error saving profile", http.StatusInternalServerError)
+		return
+	}
+
+	setFlash(r, "Profile saved.")
+	http.Redirect(w, r, "/admin/profile", http.StatusSeeOther)
+}
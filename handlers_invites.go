@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hiimtaylorjones/hiimtaylor-go/mailer"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/hiimtaylorjones/hiimtaylor-go/passwords"
+	"github.com/hiimtaylorjones/hiimtaylor-go/queries"
+	"github.com/hiimtaylorjones/hiimtaylor-go/tokens"
+	"github.com/hiimtaylorjones/hiimtaylor-go/validation"
+)
+
+// inviteTTL is how long an invite link works.
+const inviteTTL = 7 * 24 * time.Hour
+
+// handleCreateInvite makes an invite link for a new user. With an email the
+// link is sent there, and the account has to use that address.
+func handleCreateInvite(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	email := strings.TrimSpace(r.FormValue("invite_email"))
+	role := models.Role(r.FormValue("invite_role"))
+	errs := validation.Account(email, "", "", false)
+	if !role.Valid() {
+		errs["role"] = "Pick a role."
+	}
+	if email != "" && !errs.Any() {
+		if _, err := queries.GetAdminByEmail(email); err == nil {
+			errs["email"] = "Someone already has an account with that email address."
+		}
+	}
+	if errs.Any() {
+		form := map[string]string{"invite_email": email, "invite_role": string(role)}
+		renderUsers(w, r, http.StatusUnprocessableEntity, form, nil, errs)
+		return
+	}
+
+	// Invite links are emailed or passed on, so like reset links they only
+	// come from SITE_URL, never the request host.
+	base, err := emailLinkBase()
+	if err != nil {
+		setFlash(r, "Set SITE_URL before creating invites, so invite links point at this site.")
+		http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+		return
+	}
+	token, hash, err := tokens.New()
+	if err != nil {
+		http.Error(w, "Error creating invite", http.StatusInternalServerError)
+		return
+	}
+	inviter := currentAdmin(r)
+	if _, err := queries.CreateInvite(email, role, inviter.ID, hash, inviteTTL); err != nil {
+		http.Error(w, "Error creating invite", http.StatusInternalServerError)
+		return
+	}
+	link := base + "/invites/" + token
+	sessionManager.Put(r.Context(), "invite_link", link)
+
+	switch {
+	case email == "":
+		setFlash(r, "Invite created. Send the link below to the person you're inviting; it works once, for a week.")
+	case !mailer.Delivers(siteMailer):
+		setFlash(r, "Invite created, but email isn't set up, so send the link below to "+email+" yourself. It works once, for a week.")
+	default:
+		sendInvite(inviter, email, role, link)
+		setFlash(r, "Invite sent to "+email+". The link works once, for a week.")
+	}
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
+
+// sendInvite emails an invite link in the background, logging failures; the
+// link is also shown to the inviter, who can pass it on another way.
+func sendInvite(inviter models.Admin, email string, role models.Role, link string) {
+	from := inviter.Name
+	if from == "" {
+		from = inviter.Email
+	}
+	msg := mailer.Message{
+		To:      email,
+		Subject: "You're invited to " + siteName,
+		Body: fmt.Sprintf("%s has invited you to join %s as %s %s.\n\n"+
+			"To set up your account, open this link within the next week:\n\n%s\n\n"+
+			"The link works once. If you weren't expecting this, you can ignore this email.\n",
+			from, siteName, article(role.Label()), strings.ToLower(role.Label()), link),
+	}
+	go func() {
+		if err := siteMailer.Send(msg); err != nil {
+			log.Printf("invite email: %v", err)
+		}
+	}()
+}
+
+// article returns "an" or "a" for the word that follows it.
+func article(word string) string {
+	if word != "" && strings.ContainsRune("AEIOUaeiou", rune(word[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+func handleRevokeInvite(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	err := queries.RevokeInvite(id)
+	if errors.Is(err, queries.ErrInviteInvalid) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error revoking invite", http.StatusInternalServerError)
+		return
+	}
+	setFlash(r, "Invite revoked. Its link no longer works.")
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
+
+// renderAcceptInvite shows the account form for an invite, or explains that
+// the link no longer works when invite is nil.
+func renderAcceptInvite(w http.ResponseWriter, r *http.Request, status int, token string, invite *models.Invite, email string, errs validation.Errors) {
+	// Keep the token out of Referer headers sent from this page.
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	renderTemplate(w, r, status, "invite.accept", map[string]any{
+		"Token":  token,
+		"Invite": invite,
+		"Email":  email,
+		"Errors": errs,
+	})
+}
+
+func handleAcceptInviteForm(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	invite, err := queries.GetInviteByToken(tokens.Hash(token))
+	if errors.Is(err, queries.ErrInviteInvalid) {
+		renderAcceptInvite(w, r, http.StatusNotFound, "", nil, "", nil)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error loading invite", http.StatusInternalServerError)
+		return
+	}
+	renderAcceptInvite(w, r, http.StatusOK, token, &invite, invite.Email, nil)
+}
+
+func handleAcceptInvite(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	token := chi.URLParam(r, "token")
+	invite, err := queries.GetInviteByToken(tokens.Hash(token))
+	if errors.Is(err, queries.ErrInviteInvalid) {
+		renderAcceptInvite(w, r, http.StatusNotFound, "", nil, "", nil)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error loading invite", http.StatusInternalServerError)
+		return
+	}
+
+	// An invite sent to an address can only be used for that address.
+	email := invite.Email
+	if email == "" {
+		email = strings.TrimSpace(r.FormValue("email"))
+	}
+	password := r.FormValue("password")
+	errs := validation.Account(email, password, r.FormValue("password_confirmation"), true)
+	if errs.Any() {
+		renderAcceptInvite(w, r, http.StatusUnprocessableEntity, token, &invite, email, errs)
+		return
+	}
+
+	hash, err := passwords.Hash(password)
+	if err != nil {
+		http.Error(w, "Error creating account", http.StatusInternalServerError)
+		return
+	}
+	admin, err := queries.AcceptInvite(tokens.Hash(token), email, hash)
+	switch {
+	case errors.Is(err, queries.ErrEmailTaken):
+		errs["email"] = "Someone already has an account with that email address."
+		renderAcceptInvite(w, r, http.StatusUnprocessableEntity, token, &invite, email, errs)
+		return
+	case errors.Is(err, queries.ErrInviteInvalid):
+		renderAcceptInvite(w, r, http.StatusNotFound, "", nil, "", nil)
+		return
+	case err != nil:
+		http.Error(w, "Error creating account", http.StatusInternalServerError)
+		return
+	}
+
+	setFlash(r, "Welcome! Your account is ready. Add your name and bio in your profile.")
+	completeLogin(w, r, admin)
+}
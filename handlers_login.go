@@ -0,0 +1,343 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/lockout"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/hiimtaylorjones/hiimtaylor-go/passwords"
+	"github.com/hiimtaylorjones/hiimtaylor-go/queries"
+	"github.com/hiimtaylorjones/hiimtaylor-go/totp"
+)
+
+// twoFactorTimeout is how long a user has to enter their authenticator code
+// after their password is accepted.
+const twoFactorTimeout = 5 * time.Minute
+
+// dummyPasswordHash is checked against when no account has the submitted
+// email, so that unknown emails take as long to reject as wrong passwords
+// and response times don't reveal which emails have accounts.
+var dummyPasswordHash, _ = passwords.Hash("not anyone's password")
+
+// loginAttempt is one sign-in attempt as seen by the throttle: its recorded
+// ID, where it came from, which email it was for, and the recent failures
+// for each other than itself.
+type loginAttempt struct {
+	id            int64
+	ip, email     string
+	byIP, byEmail queries.FailedLogins
+}
+
+// newLoginAttempt records an attempt from r for email as failed, then looks
+// up the other recent failures for its address and email. Recording first
+// means parallel attempts count each other, so a burst of guesses can't all
+// get through on the same count. An attempt the throttle turns away is
+// removed again, since its credentials are never checked. Emails are
+// compared case-insensitively so "Me@example.com" shares a count with
+// "me@example.com". Attempts without an email, such as with an unknown
+// passkey, are throttled by address only; counting them all under "" would
+// let anyone lock that shared count for everybody.
+func newLoginAttempt(r *http.Request, email string) (loginAttempt, error) {
+	a := loginAttempt{ip: clientIP(r), email: strings.ToLower(strings.TrimSpace(email))}
+	var err error
+	if a.id, err = queries.StartLoginAttempt(a.ip, a.email); err != nil {
+		return a, err
+	}
+	if a.byIP, err = queries.FailedLoginsForIP(a.ip, lockout.IP.Window, a.id); err != nil {
+		return a, err
+	}
+	if a.email != "" {
+		if a.byEmail, err = queries.FailedLoginsForEmail(a.email, lockout.Account.Window, a.id); err != nil {
+			return a, err
+		}
+	}
+	if a.wait() > 0 {
+		a.forget()
+	}
+	return a, nil
+}
+
+// wait returns how long the client must wait before this attempt is allowed.
+func (a loginAttempt) wait() time.Duration {
+	retry := lockout.IP.RetryAt(a.byIP.Count, a.byIP.Last)
+	if t := lockout.Account.RetryAt(a.byEmail.Count, a.byEmail.Last); t.After(retry) {
+		retry = t
+	}
+	return time.Until(retry)
+}
+
+// failed logs a lockout event when the attempt is the failure that locks out
+// the email or address. The attempt itself is already recorded as failed.
+func (a loginAttempt) failed() {
+	now := time.Now()
+	if n := a.byEmail.Count + 1; a.email != "" && lockout.Account.LocksOut(n) {
+		if err := queries.RecordLockout("account", a.ip, a.email, n, now.Add(lockout.Account.Delay(n))); err != nil {
+			log.Printf("login throttle: %v", err)
+		}
+	}
+	if n := a.byIP.Count + 1; lockout.IP.LocksOut(n) {
+		if err := queries.RecordLockout("ip", a.ip, a.email, n, now.Add(lockout.IP.Delay(n))); err != nil {
+			log.Printf("login throttle: %v", err)
+		}
+	}
+}
+
+// succeeded records the attempt as successful, clearing the email's count.
+func (a loginAttempt) succeeded() {
+	if err := queries.MarkLoginSucceeded(a.id); err != nil {
+		log.Printf("login throttle: %v", err)
+	}
+}
+
+// forget removes the attempt, for one that ends up neither failing nor
+// succeeding: turned away by the throttle, for a disabled account, or with
+// the password right but the second factor still to come, which is then
+// counted as an attempt of its own.
+func (a loginAttempt) forget() {
+	if err := queries.DeleteLoginAttempt(a.id); err != nil {
+		log.Printf("login throttle: %v", err)
+	}
+}
+
+// setRetryAfter tells the client how many seconds to wait, rounding up.
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+}
+
+// renderThrottled re-renders a sign-in page with 429 when the client has to
+// wait before trying again.
+func renderThrottled(w http.ResponseWriter, r *http.Request, name string, wait time.Duration) {
+	setRetryAfter(w, wait)
+	renderTemplate(w, r, http.StatusTooManyRequests, name, map[string]any{
+		"Error": "Too many failed attempts. Try again in " + waitText(wait) + ".",
+	})
+}
+
+func handleLoginForm(w http.ResponseWriter, r *http.Request) {
+	renderTemplate(w, r, http.StatusOK, "login", nil)
+}
+
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	email := r.FormValue("email")
+	password := r.FormValue("password")
+
+	attempt, err := newLoginAttempt(r, email)
+	if err != nil {
+		log.Printf("login throttle: %v", err)
+		http.Error(w, "Error signing in", http.StatusInternalServerError)
+		return
+	}
+	if wait := attempt.wait(); wait > 0 {
+		renderThrottled(w, r, "login", wait)
+		return
+	}
+
+	admin, err := queries.GetAdminByEmail(email)
+	if err != nil {
+		passwords.Verify(dummyPasswordHash, password)
+		attempt.failed()
+		renderTemplate(w, r, http.StatusOK, "login", map[string]any{"Error": "Invalid email or password"})
+		return
+	}
+
+	ok, err := passwords.Verify(admin.EncryptedPassword, password)
+	if err != nil {
+		log.Printf("password hash for admin %d: %v", admin.ID, err)
+	}
+	if !ok {
+		attempt.failed()
+		renderTemplate(w, r, http.StatusOK, "login", map[string]any{"Error": "Invalid email or password"})
+		return
+	}
+	if admin.Disabled() {
+		attempt.forget()
+		renderTemplate(w, r, http.StatusOK, "login", map[string]any{"Error": "This account has been disabled."})
+		return
+	}
+	upgradePasswordHash(admin, password)
+
+	// With two-factor on, the password only gets the user as far as the
+	// code form. The code form records its own attempt, which isn't counted
+	// as a success until the code is right, so the throttle keeps covering
+	// guesses at the code.
+	if admin.TwoFactorEnabled() {
+		attempt.forget()
+		startTwoFactor(w, r, admin)
+		return
+	}
+
+	attempt.succeeded()
+	completeLogin(w, r, admin)
+}
+
+// startTwoFactor sends admin, who has passed the first factor, on to the
+// code form.
+func startTwoFactor(w http.ResponseWriter, r *http.Request, admin models.Admin) {
+	sessionManager.Put(r.Context(), "pending_admin_id", fmt.Sprintf("%d", admin.ID))
+	// Times are stored as Unix seconds; the session codec can't hold a
+	// time.Time.
+	sessionManager.Put(r.Context(), "pending_admin_at", time.Now().Unix())
+	http.Redirect(w, r, "/login/two-factor", http.StatusSeeOther)
+}
+
+// upgradePasswordHash rehashes admin's password with the current scheme and
+// parameters if the stored hash was made with older ones. It runs right
+// after a successful check, the only time the plaintext is available.
+// Failing only means trying again next sign-in, so errors are just logged.
+func upgradePasswordHash(admin models.Admin, password string) {
+	if !passwords.NeedsRehash(admin.EncryptedPassword) {
+		return
+	}
+	hash, err := passwords.Hash(password)
+	if err == nil {
+		err = queries.RehashAdminPassword(admin.ID, admin.EncryptedPassword, hash)
+	}
+	if err != nil && !errors.Is(err, queries.ErrAdminNotFound) {
+		log.Printf("could not rehash password for admin %d: %v", admin.ID, err)
+	}
+}
+
+// signIn starts a signed-in session for admin. The session token is
+// replaced first so that a token planted before sign-in (session fixation)
+// never becomes a signed-in one.
+func signIn(r *http.Request, admin models.Admin) error {
+	if err := sessionManager.RenewToken(r.Context()); err != nil {
+		return err
+	}
+	sessionManager.Put(r.Context(), "admin_id", fmt.Sprintf("%d", admin.ID))
+	recordSession(r)
+	return nil
+}
+
+// completeLogin signs admin in and sends them to the home page.
+func completeLogin(w http.ResponseWriter, r *http.Request, admin models.Admin) {
+	if err := signIn(r, admin); err != nil {
+		http.Error(w, "Error signing in", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// pendingAdmin returns the user whose password was accepted but who still
+// has to enter an authenticator code, if they did so recently enough.
+func pendingAdmin(r *http.Request) (models.Admin, bool) {
+	id, err := strconv.Atoi(sessionManager.GetString(r.Context(), "pending_admin_id"))
+	if err != nil {
+		return models.Admin{}, false
+	}
+	if time.Since(time.Unix(sessionManager.GetInt64(r.Context(), "pending_admin_at"), 0)) > twoFactorTimeout {
+		return models.Admin{}, false
+	}
+	admin, err := queries.GetAdminByID(id)
+	if err != nil || admin.Disabled() || !admin.TwoFactorEnabled() {
+		return models.Admin{}, false
+	}
+	return admin, true
+}
+
+// clearPendingAdmin ends the two-factor step, whether or not it succeeded.
+func clearPendingAdmin(r *http.Request) {
+	sessionManager.Remove(r.Context(), "pending_admin_id")
+	sessionManager.Remove(r.Context(), "pending_admin_at")
+}
+
+func handleTwoFactorForm(w http.ResponseWriter, r *http.Request) {
+	if _, ok := pendingAdmin(r); !ok {
+		clearPendingAdmin(r)
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	renderTemplate(w, r, http.StatusOK, "login.two_factor", nil)
+}
+
+func handleTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	admin, ok := pendingAdmin(r)
+	if !ok {
+		clearPendingAdmin(r)
+		setFlash(r, "That took too long. Sign in again.")
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	attempt, err := newLoginAttempt(r, admin.Email)
+	if err != nil {
+		log.Printf("login throttle: %v", err)
+		http.Error(w, "Error signing in", http.StatusInternalServerError)
+		return
+	}
+	if wait := attempt.wait(); wait > 0 {
+		renderThrottled(w, r, "login.two_factor", wait)
+		return
+	}
+
+	// Codes are often typed or pasted with spaces, as in "123 456", so
+	// they're dropped before telling an authenticator code from a longer
+	// recovery code.
+	code := strings.Join(strings.Fields(r.FormValue("code")), "")
+	recovery := len(code) > totp.Digits
+	var verified bool
+	if recovery {
+		verified, err = queries.UseRecoveryCode(admin.ID, totp.HashRecoveryCode(code))
+	} else if step, ok := totp.Verify(admin.TOTPSecret, code, time.Now()); ok {
+		verified, err = queries.UseTOTPStep(admin.ID, step)
+	}
+	if err != nil {
+		http.Error(w, "Error signing in", http.StatusInternalServerError)
+		return
+	}
+	if !verified {
+		attempt.failed()
+		renderTemplate(w, r, http.StatusOK, "login.two_factor", map[string]any{"Error": "That code didn't work. Try the current one from your app."})
+		return
+	}
+
+	clearPendingAdmin(r)
+	attempt.succeeded()
+	if recovery {
+		left, err := queries.CountRecoveryCodes(admin.ID)
+		if err == nil {
+			setFlash(r, fmt.Sprintf("Signed in with a recovery code. You have %d left; make new ones in Settings if you're running low.", left))
+		}
+	}
+	completeLogin(w, r, admin)
+}
+
+// clientIP returns the address a request came from, without its port. Behind
+// a proxy this is the proxy unless TRUST_PROXY_HEADERS is set (see main).
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// waitText describes a wait as whole seconds or minutes, rounding up.
+func waitText(d time.Duration) string {
+	if d <= time.Minute {
+		n := int((d + time.Second - 1) / time.Second)
+		if n == 1 {
+			return "1 second"
+		}
+		return fmt.Sprintf("%d seconds", n)
+	}
+	n := int((d + time.Minute - 1) / time.Minute)
+	return fmt.Sprintf("%d minutes", n)
+}
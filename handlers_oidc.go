@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/oidc"
+	"github.com/hiimtaylorjones/hiimtaylor-go/queries"
+)
+
+// oidcTimeout is how long a user has to finish signing in at the identity
+// provider.
+const oidcTimeout = 10 * time.Minute
+
+var (
+	// oidcClient signs admins in with the team's identity provider. It is
+	// nil, and the option hidden, unless configureOIDC found one.
+	oidcClient *oidc.Client
+	// oidcName names the provider on the login page.
+	oidcName string
+)
+
+// oidcRedirectURI is where the provider sends users back to. It has to be
+// registered with the provider exactly as written.
+func oidcRedirectURI(r *http.Request) string {
+	return siteURL(r) + "/login/oidc/callback"
+}
+
+// renderOIDCError shows the login page with an explanation of why single
+// sign-on didn't work.
+func renderOIDCError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	renderTemplate(w, r, status, "login", map[string]any{"Error": message})
+}
+
+func handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if oidcClient == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		v, err := oidc.NewRandom()
+		if err != nil {
+			http.Error(w, "Error signing in", http.StatusInternalServerError)
+			return
+		}
+		values[i] = v
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	authURL, err := oidcClient.AuthCodeURL(r.Context(), oidcRedirectURI(r), state, nonce, verifier)
+	if err != nil {
+		log.Printf("oidc: %v", err)
+		renderOIDCError(w, r, http.StatusBadGateway, "Couldn't reach "+oidcName+". Try again, or sign in with your password.")
+		return
+	}
+
+	ctx := r.Context()
+	sessionManager.Put(ctx, "oidc_state", state)
+	sessionManager.Put(ctx, "oidc_nonce", nonce)
+	sessionManager.Put(ctx, "oidc_verifier", verifier)
+	sessionManager.Put(ctx, "oidc_started_at", time.Now().Unix())
+	http.Redirect(w, r, authURL, http.StatusSeeOther)
+}
+
+func handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if oidcClient == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Each flow's values are used once, whatever happens next.
+	ctx := r.Context()
+	state := sessionManager.PopString(ctx, "oidc_state")
+	nonce := sessionManager.PopString(ctx, "oidc_nonce")
+	verifier := sessionManager.PopString(ctx, "oidc_verifier")
+	startedAt := time.Unix(sessionManager.GetInt64(ctx, "oidc_started_at"), 0)
+	sessionManager.Remove(ctx, "oidc_started_at")
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		log.Printf("oidc: provider returned %s: %s", e, q.Get("error_description"))
+		renderOIDCError(w, r, http.StatusUnauthorized, "Signing in with "+oidcName+" was cancelled or failed.")
+		return
+	}
+	// The state ties the callback to the flow this browser started, so a
+	// link from someone else can't sign this browser in to their account.
+	// It is also missing if the session cookie didn't survive the trip to
+	// the provider, as happens with SESSION_COOKIE_SAMESITE=strict.
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(q.Get("state"))) != 1 ||
+		time.Since(startedAt) > oidcTimeout {
+		renderOIDCError(w, r, http.StatusBadRequest, "That sign in took too long or didn't start here. Try again.")
+		return
+	}
+
+	claims, err := oidcClient.Exchange(ctx, oidcRedirectURI(r), q.Get("code"), verifier, nonce)
+	if err != nil {
+		log.Printf("oidc: %v", err)
+		renderOIDCError(w, r, http.StatusUnauthorized, "Signing in with "+oidcName+" failed.")
+		return
+	}
+	// Accounts are matched by email, so only an address the provider has
+	// verified will do.
+	if claims.Email == "" || !claims.EmailVerified {
+		renderOIDCError(w, r, http.StatusForbidden, oidcName+" didn't share a verified email address, so there's no way to tell which account is yours.")
+		return
+	}
+
+	admin, err := queries.GetAdminByEmail(claims.Email)
+	if err != nil {
+		renderOIDCError(w, r, http.StatusForbidden, "No account here uses "+claims.Email+". Ask an admin to add you.")
+		return
+	}
+	if admin.Disabled() {
+		renderOIDCError(w, r, http.StatusForbidden, "This account has been disabled.")
+		return
+	}
+
+	// The provider may have accepted a password alone, so an account with
+	// two-factor on still needs its code, as after a password here.
+	if admin.TwoFactorEnabled() {
+		startTwoFactor(w, r, admin)
+		return
+	}
+	attempt, err := newLoginAttempt(r, admin.Email)
+	if err != nil {
+		log.Printf("login throttle: %v", err)
+	} else {
+		attempt.succeeded()
+	}
+	completeLogin(w, r, admin)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/hiimtaylorjones/hiimtaylor-go/queries"
+	"github.com/hiimtaylorjones/hiimtaylor-go/webauthn"
+)
+
+// relyingParty describes this site to WebAuthn. Passkeys are bound to the
+// host name, so SITE_URL should be set wherever the request host can vary.
+func relyingParty(r *http.Request) webauthn.RelyingParty {
+	origin := siteURL(r)
+	u, _ := url.Parse(origin)
+	return webauthn.RelyingParty{ID: u.Hostname(), Name: siteName, Origin: origin}
+}
+
+// webauthnUserID is the user handle stored with a user's passkeys: their ID,
+// which identifies them without revealing anything personal.
+func webauthnUserID(adminID int) []byte {
+	return []byte(strconv.Itoa(adminID))
+}
+
+// newWebAuthnChallenge creates a challenge and keeps it in the session under
+// key until the browser answers it.
+func newWebAuthnChallenge(r *http.Request, key string) ([]byte, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+	sessionManager.Put(r.Context(), key, challenge)
+	return challenge, nil
+}
+
+// popWebAuthnChallenge returns the challenge stored under key, removing it so
+// it can only be answered once.
+func popWebAuthnChallenge(r *http.Request, key string) []byte {
+	challenge, _ := sessionManager.Pop(r.Context(), key).([]byte)
+	return challenge
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func handlePasskeyLoginOptions(w http.ResponseWriter, r *http.Request) {
+	challenge, err := newWebAuthnChallenge(r, "passkey_login_challenge")
+	if err != nil {
+		http.Error(w, "Error starting passkey sign in", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, relyingParty(r).RequestOptions(challenge))
+}
+
+func handlePasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	var resp webauthn.AssertionResponse
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&resp); err != nil {
+		http.Error(w, "Invalid passkey response", http.StatusBadRequest)
+		return
+	}
+	challenge := popWebAuthnChallenge(r, "passkey_login_challenge")
+	if challenge == nil {
+		http.Error(w, "That took too long. Try again.", http.StatusBadRequest)
+		return
+	}
+
+	passkey, err := queries.GetPasskeyByCredentialID(resp.ID)
+	if err != nil && !errors.Is(err, queries.ErrPasskeyNotFound) {
+		http.Error(w, "Error signing in", http.StatusInternalServerError)
+		return
+	}
+	var admin models.Admin
+	if err == nil {
+		if admin, err = queries.GetAdminByID(passkey.AdminID); err != nil {
+			http.Error(w, "Error signing in", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Unknown passkeys have no email, so only the address's count applies
+	// (see newLoginAttempt).
+	attempt, err := newLoginAttempt(r, admin.Email)
+	if err != nil {
+		log.Printf("login throttle: %v", err)
+		http.Error(w, "Error signing in", http.StatusInternalServerError)
+		return
+	}
+	if wait := attempt.wait(); wait > 0 {
+		setRetryAfter(w, wait)
+		http.Error(w, "Too many failed attempts. Try again in "+waitText(wait)+".", http.StatusTooManyRequests)
+		return
+	}
+	if admin.ID == 0 {
+		attempt.failed()
+		http.Error(w, "That passkey isn't registered here. Sign in with your password and add it in Settings.", http.StatusUnauthorized)
+		return
+	}
+
+	if len(resp.Response.UserHandle) > 0 && !bytes.Equal(resp.Response.UserHandle, webauthnUserID(admin.ID)) {
+		attempt.failed()
+		http.Error(w, "Passkey sign in failed.", http.StatusUnauthorized)
+		return
+	}
+
+	cred := webauthn.Credential{ID: passkey.CredentialID, PublicKey: passkey.PublicKey, SignCount: passkey.SignCount}
+	signCount, err := relyingParty(r).VerifyAssertion(challenge, cred, resp)
+	if err != nil {
+		log.Printf("passkey %d for admin %d: %v", passkey.ID, admin.ID, err)
+		attempt.failed()
+		http.Error(w, "Passkey sign in failed.", http.StatusUnauthorized)
+		return
+	}
+	if admin.Disabled() {
+		attempt.forget()
+		http.Error(w, "This account has been disabled.", http.StatusForbidden)
+		return
+	}
+
+	if err := queries.UsePasskey(passkey.ID, signCount); err != nil {
+		log.Printf("could not record passkey use: %v", err)
+	}
+	attempt.succeeded()
+	// A passkey with user verification is both factors, so there is no
+	// two-factor step.
+	if err := signIn(r, admin); err != nil {
+		http.Error(w, "Error signing in", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]string{"redirect": "/"})
+}
+
+func handlePasskeyOptions(w http.ResponseWriter, r *http.Request) {
+	admin := currentAdmin(r)
+	passkeys, err := queries.ListPasskeys(admin.ID)
+	if err != nil {
+		http.Error(w, "Error loading passkeys", http.StatusInternalServerError)
+		return
+	}
+	exclude := make([][]byte, len(passkeys))
+	for i, p := range passkeys {
+		exclude[i] = p.CredentialID
+	}
+
+	challenge, err := newWebAuthnChallenge(r, "passkey_register_challenge")
+	if err != nil {
+		http.Error(w, "Error starting passkey setup", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, relyingParty(r).CreationOptions(challenge, webauthnUserID(admin.ID), admin.Email, admin.DisplayName(), exclude))
+}
+
+// passkeyRegistration is what the settings page posts after the browser
+// creates a passkey.
+type passkeyRegistration struct {
+	Name       string                        `json:"name"`
+	Credential webauthn.RegistrationResponse `json:"credential"`
+}
+
+func handleCreatePasskey(w http.ResponseWriter, r *http.Request) {
+	var req passkeyRegistration
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		http.Error(w, "Invalid passkey response", http.StatusBadRequest)
+		return
+	}
+	challenge := popWebAuthnChallenge(r, "passkey_register_challenge")
+	if challenge == nil {
+		http.Error(w, "That took too long. Try again.", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+	if len(name) > 100 {
+		http.Error(w, "Name must be 100 characters or fewer.", http.StatusUnprocessableEntity)
+		return
+	}
+
+	cred, err := relyingParty(r).VerifyRegistration(challenge, req.Credential)
+	if err != nil {
+		log.Printf("passkey registration for admin %d: %v", currentAdmin(r).ID, err)
+		http.Error(w, "Your browser's response couldn't be verified. Try again.", http.StatusBadRequest)
+		return
+	}
+	if _, err := queries.CreatePasskey(currentAdmin(r).ID, cred.ID, cred.PublicKey, cred.SignCount, name); err != nil {
+		http.Error(w, "Error saving passkey", http.StatusInternalServerError)
+		return
+	}
+
+	setFlash(r, "Passkey added.")
+	writeJSON(w, map[string]string{"redirect": "/admin/settings#passkeys"})
+}
+
+func handleDeletePasskey(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	err := queries.DeletePasskey(id, currentAdmin(r).ID)
+	if errors.Is(err, queries.ErrPasskeyNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error removing passkey", http.StatusInternalServerError)
+		return
+	}
+
+	setFlash(r, "Passkey removed.")
+	http.Redirect(w, r, "/admin/settings#passkeys", http.StatusSeeOther)
+}
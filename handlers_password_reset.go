@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hiimtaylorjones/hiimtaylor-go/mailer"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/hiimtaylorjones/hiimtaylor-go/passwords"
+	"github.com/hiimtaylorjones/hiimtaylor-go/queries"
+	"github.com/hiimtaylorjones/hiimtaylor-go/tokens"
+	"github.com/hiimtaylorjones/hiimtaylor-go/validation"
+)
+
+const (
+	// passwordResetTTL is how long an emailed reset link works.
+	passwordResetTTL = time.Hour
+	// passwordResetInterval is the least time between reset emails to one
+	// account, so the form can't be used to flood someone's inbox.
+	passwordResetInterval = 5 * time.Minute
+)
+
+func handleForgotPasswordForm(w http.ResponseWriter, r *http.Request) {
+	renderTemplate(w, r, http.StatusOK, "login.forgot", nil)
+}
+
+func handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	email := strings.TrimSpace(r.FormValue("email"))
+	if admin, err := queries.GetAdminByEmail(email); err == nil && !admin.Disabled() {
+		if err := sendPasswordReset(admin); err != nil {
+			log.Printf("password reset for admin %d: %v", admin.ID, err)
+		}
+	}
+
+	// The reply is the same whether or not the account exists, so the form
+	// can't be used to find out which emails have accounts.
+	setFlash(r, "If there's an account for that email, we've sent it a link to reset the password. The link works for an hour.")
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// sendPasswordReset emails admin a reset link, unless one was sent very
+// recently. Nothing is sent unless SITE_URL says where the link should go
+// and a mail server is configured to take it there.
+func sendPasswordReset(admin models.Admin) error {
+	base, err := emailLinkBase()
+	if err != nil {
+		return err
+	}
+	if !mailer.Delivers(siteMailer) {
+		return errNoMailer
+	}
+	recent, err := queries.PasswordResetRequestedSince(admin.ID, time.Now().Add(-passwordResetInterval))
+	if err != nil || recent {
+		return err
+	}
+
+	token, hash, err := tokens.New()
+	if err != nil {
+		return err
+	}
+	if err := queries.CreatePasswordReset(admin.ID, hash, passwordResetTTL); err != nil {
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      admin.Email,
+		Subject: "Reset your " + siteName + " password",
+		Body: fmt.Sprintf("Someone, hopefully you, asked to reset the password for %s.\n\n"+
+			"To choose a new password, open this link within the next hour:\n\n%s\n\n"+
+			"If you didn't ask for this, you can ignore this email; your password won't change.\n",
+			admin.Email, base+"/login/reset/"+token),
+	}
+	// Sent in the background so that the response takes as long as it does
+	// for an unknown email.
+	go func() {
+		if err := siteMailer.Send(msg); err != nil {
+			log.Printf("password reset email: %v", err)
+		}
+	}()
+	return nil
+}
+
+// renderResetPassword shows the new password form for token, or explains
+// that the link no longer works.
+func renderResetPassword(w http.ResponseWriter, r *http.Request, status int, token string, errs validation.Errors) {
+	// Keep the token out of Referer headers sent from this page.
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	renderTemplate(w, r, status, "login.reset", map[string]any{
+		"Token":   token,
+		"Invalid": status == http.StatusNotFound,
+		"Errors":  errs,
+	})
+}
+
+func handleResetPasswordForm(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	_, err := queries.GetPasswordResetAdmin(tokens.Hash(token))
+	if errors.Is(err, queries.ErrResetTokenInvalid) {
+		renderResetPassword(w, r, http.StatusNotFound, "", nil)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error loading password reset", http.StatusInternalServerError)
+		return
+	}
+	renderResetPassword(w, r, http.StatusOK, token, nil)
+}
+
+func handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	token := chi.URLParam(r, "token")
+	password := r.FormValue("password")
+	errs := validation.Account("", password, r.FormValue("password_confirmation"), false)
+	if password == "" {
+		errs["password"] = "Password is required."
+	}
+	if errs.Any() {
+		renderResetPassword(w, r, http.StatusUnprocessableEntity, token, errs)
+		return
+	}
+
+	hash, err := passwords.Hash(password)
+	if err != nil {
+		http.Error(w, "Error resetting password", http.StatusInternalServerError)
+		return
+	}
+	adminID, err := queries.ResetPassword(tokens.Hash(token), hash)
+	if errors.Is(err, queries.ErrResetTokenInvalid) {
+		renderResetPassword(w, r, http.StatusNotFound, "", nil)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error resetting password", http.StatusInternalServerError)
+		return
+	}
+
+	// Whoever knew the old password may still be signed in, so end every
+	// session for the account, and this browser's too.
+	if err := signOutOtherSessions(r, adminID); err != nil {
+		log.Printf("could not sign out sessions after password reset: %v", err)
+	}
+	if err := signOut(r); err != nil {
+		http.Error(w, "Error signing out", http.StatusInternalServerError)
+		return
+	}
+
+	setFlash(r, "Your password has been reset and you've been signed out everywhere. Sign in with your new password.")
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// sessionKeys are everything signIn puts in the session, which signOut
+// removes again.
+var sessionKeys = []string{"admin_id", "session_id", "signed_in_at", "ip", "user_agent"}
+
+// recordSession stores what the sessions page shows about this sign-in. The
+// session_id identifies the session on that page; the session token itself
+// is never shown.
+func recordSession(r *http.Request) {
+	b := make([]byte, 16)
+	rand.Read(b)
+	ua := r.UserAgent()
+	if len(ua) > 300 {
+		ua = ua[:300]
+	}
+
+	ctx := r.Context()
+	sessionManager.Put(ctx, "session_id", hex.EncodeToString(b))
+	sessionManager.Put(ctx, "signed_in_at", time.Now().Unix())
+	sessionManager.Put(ctx, "ip", clientIP(r))
+	sessionManager.Put(ctx, "user_agent", ua)
+}
+
+// signOut ends the signed-in session on this request, keeping the session
+// itself for the CSRF token and flash messages but under a new token.
+func signOut(r *http.Request) error {
+	for _, key := range sessionKeys {
+		sessionManager.Remove(r.Context(), key)
+	}
+	return sessionManager.RenewToken(r.Context())
+}
+
+// activeSession is one place a user is signed in.
+type activeSession struct {
+	ID         string
+	IP         string
+	UserAgent  string
+	SignedInAt time.Time
+	Current    bool
+}
+
+// Device describes the browser and system from the user agent, roughly.
+func (s activeSession) Device() string {
+	ua := s.UserAgent
+	if ua == "" {
+		return "Unknown device"
+	}
+
+	browser := "Unknown browser"
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"}, {"Firefox/", "Firefox"}, {"Chrome/", "Chrome"}, {"Safari/", "Safari"},
+	} {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	system := "unknown system"
+	for _, o := range []struct{ token, name string }{
+		{"iPhone", "iPhone"}, {"iPad", "iPad"}, {"Android", "Android"},
+		{"Mac OS X", "macOS"}, {"Windows", "Windows"}, {"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			system = o.name
+			break
+		}
+	}
+	return browser + " on " + system
+}
+
+// storedSession is one of an admin's sessions as last saved.
+type storedSession struct {
+	token  string
+	values map[string]any
+}
+
+// adminSessions returns the sessions adminID is signed in to, found through
+// the store's admin index rather than by reading every session.
+func adminSessions(adminID int) ([]storedSession, error) {
+	found, err := sessionStore.ForAdmin(adminID)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]storedSession, 0, len(found))
+	for token, b := range found {
+		_, values, err := sessionManager.Codec.Decode(b)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, storedSession{token: token, values: values})
+	}
+	return sessions, nil
+}
+
+// signOutOtherSessions ends every session adminID is signed in to except
+// the one on r.
+func signOutOtherSessions(r *http.Request, adminID int) error {
+	_, err := sessionStore.DeleteForAdmin(adminID, sessionManager.Token(r.Context()))
+	return err
+}
+
+func handleSessions(w http.ResponseWriter, r *http.Request) {
+	current := sessionManager.GetString(r.Context(), "session_id")
+	stored, err := adminSessions(currentAdmin(r).ID)
+	if err != nil {
+		http.Error(w, "Error loading sessions", http.StatusInternalServerError)
+		return
+	}
+	sessions := make([]activeSession, 0, len(stored))
+	for _, ss := range stored {
+		s := activeSession{}
+		s.ID, _ = ss.values["session_id"].(string)
+		s.IP, _ = ss.values["ip"].(string)
+		s.UserAgent, _ = ss.values["user_agent"].(string)
+		// Sessions from before sign-in times were recorded have none.
+		if at, _ := ss.values["signed_in_at"].(int64); at != 0 {
+			s.SignedInAt = time.Unix(at, 0)
+		}
+		s.Current = s.ID != "" && s.ID == current
+		sessions = append(sessions, s)
+	}
+	slices.SortFunc(sessions, func(a, b activeSession) int {
+		return b.SignedInAt.Compare(a.SignedInAt)
+	})
+
+	renderTemplate(w, r, http.StatusOK, "admin.sessions", map[string]any{"Sessions": sessions})
+}
+
+func handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "sid")
+	if id == sessionManager.GetString(r.Context(), "session_id") {
+		http.Error(w, "Use Sign out to end this session.", http.StatusBadRequest)
+		return
+	}
+
+	stored, err := adminSessions(currentAdmin(r).ID)
+	if err != nil {
+		http.Error(w, "Error signing out session", http.StatusInternalServerError)
+		return
+	}
+	i := slices.IndexFunc(stored, func(ss storedSession) bool {
+		return ss.values["session_id"] == id
+	})
+	if i < 0 {
+		http.NotFound(w, r)
+		return
+	}
+	if err := sessionStore.Delete(stored[i].token); err != nil {
+		http.Error(w, "Error signing out session", http.StatusInternalServerError)
+		return
+	}
+
+	setFlash(r, "Session signed out.")
+	http.Redirect(w, r, "/admin/sessions", http.StatusSeeOther)
+}
+
+// handleSignOutEverywhere ends all of the user's sessions, this one
+// included.
+func handleSignOutEverywhere(w http.ResponseWriter, r *http.Request) {
+	if err := signOutOtherSessions(r, currentAdmin(r).ID); err != nil {
+		http.Error(w, "Error signing out", http.StatusInternalServerError)
+		return
+	}
+	if err := signOut(r); err != nil {
+		http.Error(w, "Error signing out", http.StatusInternalServerError)
+		return
+	}
+
+	setFlash(r, "Signed out everywhere.")
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
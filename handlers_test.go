@@ -3,16 +3,35 @@ package main
 import (
 	"bytes"
 	"context"
+	"io"
+	"mime/quotedprintable"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi/v5"
 	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+	"github.com/hiimtaylorjones/hiimtaylor-go/lockout"
+	"github.com/hiimtaylorjones/hiimtaylor-go/mailer"
+	"github.com/hiimtaylorjones/hiimtaylor-go/mailer/mailertest"
+	authmiddleware "github.com/hiimtaylorjones/hiimtaylor-go/middleware"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/hiimtaylorjones/hiimtaylor-go/oidc"
+	"github.com/hiimtaylorjones/hiimtaylor-go/oidc/oidctest"
+	"github.com/hiimtaylorjones/hiimtaylor-go/passwords"
+	"github.com/hiimtaylorjones/hiimtaylor-go/queries"
+	"github.com/hiimtaylorjones/hiimtaylor-go/sessionstore"
+	"github.com/hiimtaylorjones/hiimtaylor-go/tokens"
+	"github.com/hiimtaylorjones/hiimtaylor-go/totp"
 	"github.com/joho/godotenv"
 )
 
@@ -25,6 +44,9 @@ func TestMain(m *testing.M) {
 	defer database.Close()
 
 	sessionManager = scs.New()
+	sessionStore = sessionstore.New(database.Pool, 0)
+	sessionManager.Store = sessionStore
+	authmiddleware.SetSessionManager(sessionManager)
 	loadTemplates()
 
 	os.Exit(m.Run())
@@ -35,14 +57,13 @@ func TestCreatePost_Success(t *testing.T) {
 		"title":     "Test Post",
 		"tagline":   "A test tageline",
 		"body":      "Hello",
-		"published": "true",
 	})
 
 	req := httptest.NewRequest("POST", "/posts", body)
 	req.Header.Set("Content-Type", contentType)
 	rr := httptest.NewRecorder()
 
-	handleCreatePost(rr, req)
+	serve(handleCreatePost, rr, req)
 
 	if rr.Code != http.StatusSeeOther {
 		t.Errorf("expected 303 SeeOther, got %d\nbody: %s", rr.Code, rr.Body.String())
@@ -64,7 +85,7 @@ func TestCreatePost_MissingMultipartEncoding(t *testing.T) {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr := httptest.NewRecorder()
 
-	handleCreatePost(rr, req)
+	serve(handleCreatePost, rr, req)
 
 	if rr.Code != http.StatusBadRequest {
 		t.Errorf("expected 400 Bad Request, got %d", rr.Code)
@@ -81,7 +102,7 @@ func TestCreatePost_UnpublishedByDefault(t *testing.T) {
 	req.Header.Set("Content-Type", contentType)
 	rr := httptest.NewRecorder()
 
-	handleCreatePost(rr, req)
+	serve(handleCreatePost, rr, req)
 
 	if rr.Code != http.StatusSeeOther {
 		t.Fatalf("expected 303, got %d: %s", rr.Code, rr.Body.String())
@@ -89,10 +110,548 @@ func TestCreatePost_UnpublishedByDefault(t *testing.T) {
 
 	slug := strings.TrimPrefix(rr.Header().Get("Location"), "/posts/")
 	t.Cleanup(func() { cleanupPostBySlug(t, slug) })
+
+	post, err := queries.GetPostBySlug(slug)
+	if err != nil {
+		t.Fatalf("Error fetching post: %v", err)
+	}
+	if post.Status != models.StatusDraft || post.Published() {
+		t.Errorf("expected a new post to be an unpublished draft, got %q", post.Status)
+	}
+}
+
+func TestCreatePost_InvalidTitleRerendersForm(t *testing.T) {
+	body, contentType := buildPostForm(t, map[string]string{
+		"title": "!!! ???",
+		"body":  "Keep what I typed",
+	})
+
+	req := httptest.NewRequest("POST", "/posts", body)
+	req.Header.Set("Content-Type", contentType)
+	rr := httptest.NewRecorder()
+
+	serve(handleCreatePost, rr, req)
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "Keep what I typed") {
+		t.Error("expected the submitted body to be preserved in the form")
+	}
+	if !strings.Contains(rr.Body.String(), "field-error") {
+		t.Error("expected a field error to be shown")
+	}
+}
+
+func TestPostTemplate(t *testing.T) {
+	tests := []struct {
+		layout string
+		want   string
+	}{
+		{layout: "", want: "posts.show"},
+		{layout: "photo-essay", want: "posts.layout.photo-essay"},
+		{layout: "does-not-exist", want: "posts.show"},
+	}
+
+	for _, tt := range tests {
+		if got := postTemplate(tt.layout); got != tt.want {
+			t.Errorf("postTemplate(%q) = %q, want %q", tt.layout, got, tt.want)
+		}
+	}
+}
+
+func TestPreview_RendersMarkdown(t *testing.T) {
+	req := httptest.NewRequest("POST", "/admin/preview",
+		bytes.NewBufferString("body=%23%23+Hello+**world**"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	handlePreview(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "<h2>Hello <strong>world</strong></h2>") {
+		t.Errorf("expected rendered markdown, got: %s", rr.Body.String())
+	}
+}
+func TestPasswordReset_EmailedLinkWorksOnce(t *testing.T) {
+	server := mailertest.NewServer(t)
+	siteMailer = mailer.SMTP{Addr: server.Addr, From: "site@example.com"}
+	t.Cleanup(func() { siteMailer = mailer.Log{} })
+	t.Setenv("SITE_URL", "https://blog.example.com")
+
+	admin, err := queries.CreateAdmin("reset-handler@example.com", "hash", models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("error creating admin: %v", err)
+	}
+	t.Cleanup(func() { queries.DeleteAdmin(admin.ID) })
+
+	r := chi.NewRouter()
+	r.Use(sessionManager.LoadAndSave)
+	r.Post("/login/forgot", handleForgotPassword)
+	r.Post("/login/reset/{token}", handleResetPassword)
+
+	form := url.Values{"email": {admin.Email}}
+	req := httptest.NewRequest("POST", "/login/forgot", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// The link must come from SITE_URL, not the Host the request names.
+	req.Host = "attacker.example"
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303 SeeOther, got %d", rr.Code)
+	}
+
+	messages := server.Wait(1, 5*time.Second)
+	if len(messages) != 1 {
+		t.Fatalf("expected one email, got %d", len(messages))
+	}
+	body, _ := io.ReadAll(quotedprintable.NewReader(messages[0].Body))
+	_, rest, ok := strings.Cut(string(body), "https://blog.example.com/login/reset/")
+	if !ok {
+		t.Fatalf("expected a reset link in the email, got %q", body)
+	}
+	token := strings.Fields(rest)[0]
+
+	reset := func() int {
+		form := url.Values{"password": {"a new long password"}, "password_confirmation": {"a new long password"}}
+		req := httptest.NewRequest("POST", "/login/reset/"+token, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	if code := reset(); code != http.StatusSeeOther {
+		t.Fatalf("expected the reset to succeed with 303 SeeOther, got %d", code)
+	}
+	if code := reset(); code != http.StatusNotFound {
+		t.Errorf("expected the used link to be rejected with 404, got %d", code)
+	}
+}
+
+func TestOIDCLogin_SignsInVerifiedEmail(t *testing.T) {
+	provider := oidctest.NewProvider(t, "blog", "secret")
+	oidcClient, oidcName = oidc.New(provider.Issuer, "blog", "secret"), "Test IdP"
+	t.Cleanup(func() { oidcClient, oidcName = nil, "" })
+
+	admin, err := queries.CreateAdmin("oidc-handler@example.com", "hash", models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("error creating admin: %v", err)
+	}
+	t.Cleanup(func() { queries.DeleteAdmin(admin.ID) })
+
+	r := chi.NewRouter()
+	r.Use(sessionManager.LoadAndSave)
+	r.Get("/login/oidc", handleOIDCLogin)
+	r.Get("/login/oidc/callback", handleOIDCCallback)
+
+	signIn := func(claims map[string]any) *httptest.ResponseRecorder {
+		provider.SignInAs(claims)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", "/login/oidc", nil))
+		if rr.Code != http.StatusSeeOther {
+			t.Fatalf("expected a redirect to the provider, got %d", rr.Code)
+		}
+
+		noFollow := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		resp, err := noFollow.Get(rr.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		callback, _ := url.Parse(resp.Header.Get("Location"))
+
+		req := httptest.NewRequest("GET", callback.RequestURI(), nil)
+		for _, c := range rr.Result().Cookies() {
+			req.AddCookie(c)
+		}
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := signIn(map[string]any{"sub": "1", "email": admin.Email, "email_verified": false}); rr.Code != http.StatusForbidden {
+		t.Errorf("expected an unverified email to be refused with 403, got %d", rr.Code)
+	}
+	if rr := signIn(map[string]any{"sub": "1", "email": "nobody@example.com", "email_verified": true}); rr.Code != http.StatusForbidden {
+		t.Errorf("expected an unknown email to be refused with 403, got %d", rr.Code)
+	}
+	if rr := signIn(map[string]any{"sub": "1", "email": admin.Email, "email_verified": true}); rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/" {
+		t.Errorf("expected a verified email to sign in with 303 SeeOther to /, got %d to %q\nbody: %s", rr.Code, rr.Header().Get("Location"), rr.Body.String())
+	}
+
+	secret, _ := totp.GenerateSecret()
+	if err := queries.EnableTwoFactor(admin.ID, secret, 0, nil); err != nil {
+		t.Fatalf("error enabling two-factor: %v", err)
+	}
+	if rr := signIn(map[string]any{"sub": "1", "email": admin.Email, "email_verified": true}); rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/login/two-factor" {
+		t.Errorf("expected an account with two-factor on to be sent to the code form, got %d to %q", rr.Code, rr.Header().Get("Location"))
+	}
+}
+
+func TestAcceptInvite_CreatesAccountOnce(t *testing.T) {
+	inviter, err := queries.CreateAdmin("invite-handler-inviter@example.com", "hash", models.RoleAdmin)
+	if err != nil {
+		t.Fatalf("error creating admin: %v", err)
+	}
+	token, hash, _ := tokens.New()
+	if _, err := queries.CreateInvite("invite-handler@example.com", models.RoleEditor, inviter.ID, hash, time.Hour); err != nil {
+		t.Fatalf("error creating invite: %v", err)
+	}
+	t.Cleanup(func() {
+		database.Pool.Exec(context.Background(), "DELETE FROM admin_invites WHERE token_hash = $1", hash)
+		if admin, err := queries.GetAdminByEmail("invite-handler@example.com"); err == nil {
+			queries.DeleteAdmin(admin.ID)
+		}
+		queries.DeleteAdmin(inviter.ID)
+	})
+
+	r := chi.NewRouter()
+	r.Use(sessionManager.LoadAndSave)
+	r.Post("/invites/{token}", handleAcceptInvite)
+
+	accept := func() int {
+		// The invite's own email wins over one typed into the form.
+		form := url.Values{"email": {"other@example.com"}, "password": {"a new long password"}, "password_confirmation": {"a new long password"}}
+		req := httptest.NewRequest("POST", "/invites/"+token, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	if code := accept(); code != http.StatusSeeOther {
+		t.Fatalf("expected the invite to be accepted with 303 SeeOther, got %d", code)
+	}
+	admin, err := queries.GetAdminByEmail("invite-handler@example.com")
+	if err != nil || admin.Role != models.RoleEditor {
+		t.Errorf("expected an editor account for the invited email, got %+v (err %v)", admin, err)
+	}
+	if code := accept(); code != http.StatusNotFound {
+		t.Errorf("expected the used invite to be rejected with 404, got %d", code)
+	}
 }
 
+func TestLogin_TwoFactorSignsIn(t *testing.T) {
+	const ip, password = "203.0.113.43", "a long test password"
+	hash, err := passwords.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := queries.CreateAdmin("two-factor-handler@example.com", hash, models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("error creating admin: %v", err)
+	}
+	t.Cleanup(func() {
+		queries.DeleteAdmin(admin.ID)
+		database.Pool.Exec(context.Background(), `DELETE FROM login_attempts WHERE ip = $1`, ip)
+	})
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The step the code was confirmed with is recorded as used, so enable
+	// with one old enough that the current code still works.
+	if err := queries.EnableTwoFactor(admin.ID, secret, totp.Step(time.Now())-totp.Skew-1, nil); err != nil {
+		t.Fatalf("error enabling two-factor: %v", err)
+	}
+
+	r := chi.NewRouter()
+	r.Use(sessionManager.LoadAndSave)
+	r.Post("/login", handleLogin)
+	r.Post("/login/two-factor", handleTwoFactor)
+	r.With(authmiddleware.RequireAdmin).Get("/admin", func(w http.ResponseWriter, r *http.Request) {})
+
+	// Each request carries the cookies from the one before, as a browser
+	// would, including the new session token issued on sign in.
+	var cookies []*http.Cookie
+	send := func(method, path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = ip + ":1234"
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		if set := rr.Result().Cookies(); len(set) > 0 {
+			cookies = set
+		}
+		return rr
+	}
+
+	rr := send("POST", "/login", url.Values{"email": {admin.Email}, "password": {password}})
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/login/two-factor" {
+		t.Fatalf("expected the password to lead to the code form, got %d to %q", rr.Code, rr.Header().Get("Location"))
+	}
+	if rr := send("GET", "/admin", nil); rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/login" {
+		t.Fatalf("expected the password alone not to sign in, got %d to %q", rr.Code, rr.Header().Get("Location"))
+	}
+
+	code, err := totp.Code(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = send("POST", "/login/two-factor", url.Values{"code": {code[:3] + " " + code[3:]}})
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/" {
+		t.Fatalf("expected the code to sign in with a redirect home, got %d to %q\nbody: %s", rr.Code, rr.Header().Get("Location"), rr.Body.String())
+	}
+	if rr := send("GET", "/admin", nil); rr.Code != http.StatusOK {
+		t.Errorf("expected to be signed in after the code, got %d", rr.Code)
+	}
+}
+
+func TestLogin_FreshVisitorCanPostTheForm(t *testing.T) {
+	const ip = "203.0.113.44"
+	t.Cleanup(func() {
+		database.Pool.Exec(context.Background(), `DELETE FROM login_attempts WHERE ip = $1`, ip)
+	})
+
+	r := chi.NewRouter()
+	r.Use(sessionManager.LoadAndSave)
+	r.Use(authmiddleware.VerifyCSRF)
+	r.Get("/login", handleLoginForm)
+	r.Post("/login", handleLogin)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/login", nil))
+	cookies := rr.Result().Cookies()
+	if rr.Code != http.StatusOK || len(cookies) == 0 {
+		t.Fatalf("expected the form with a new session cookie, got %d with %d cookies", rr.Code, len(cookies))
+	}
+	_, rest, ok := strings.Cut(rr.Body.String(), `name="`+authmiddleware.CSRFFieldName+`" value="`)
+	if !ok {
+		t.Fatal("expected the form to carry a CSRF token")
+	}
+	token, _, _ := strings.Cut(rest, `"`)
+
+	form := url.Values{authmiddleware.CSRFFieldName: {token}, "email": {"fresh-visitor@example.com"}, "password": {"wrong"}}
+	req := httptest.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = ip + ":1234"
+	req.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code == http.StatusForbidden {
+		t.Fatal("expected the token from the form to have been saved with the session")
+	}
+	if !strings.Contains(rr.Body.String(), "Invalid email or password") {
+		t.Errorf("expected the sign in to be checked, got %d", rr.Code)
+	}
+}
+
+func TestSignOutEverywhere_SurvivesInFlightRequests(t *testing.T) {
+	admin, err := queries.CreateAdmin("sign-out-everywhere@example.com", "hash", models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("error creating admin: %v", err)
+	}
+	t.Cleanup(func() { queries.DeleteAdmin(admin.ID) })
+
+	r := chi.NewRouter()
+	r.Use(sessionManager.LoadAndSave)
+	r.Group(func(r chi.Router) {
+		r.Use(authmiddleware.RequireAdmin)
+		r.Get("/admin", func(w http.ResponseWriter, r *http.Request) {})
+		r.Post("/admin/sessions/delete-all", handleSignOutEverywhere)
+	})
+
+	// signedIn returns a new session signed in as admin.
+	signedIn := func() string {
+		ctx, err := sessionManager.Load(context.Background(), "")
+		if err != nil {
+			t.Fatalf("error loading session: %v", err)
+		}
+		sessionManager.Put(ctx, "admin_id", strconv.Itoa(admin.ID))
+		token, _, err := sessionManager.Commit(ctx)
+		if err != nil {
+			t.Fatalf("error saving session: %v", err)
+		}
+		return token
+	}
+	send := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.AddCookie(&http.Cookie{Name: sessionManager.Cookie.Name, Value: token})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	stolen, mine := signedIn(), signedIn()
+
+	// A request on the stolen session starts before the sign out and saves
+	// the session after it.
+	inFlight, err := sessionManager.Load(context.Background(), stolen)
+	if err != nil {
+		t.Fatalf("error loading session: %v", err)
+	}
+	if rr := send("POST", "/admin/sessions/delete-all", mine); rr.Code != http.StatusSeeOther {
+		t.Fatalf("expected sign out everywhere to redirect, got %d", rr.Code)
+	}
+	sessionManager.Put(inFlight, "flash", "late")
+	if _, _, err := sessionManager.Commit(inFlight); err != nil {
+		t.Fatalf("error saving session: %v", err)
+	}
+
+	for name, token := range map[string]string{"stolen": stolen, "current": mine} {
+		if rr := send("GET", "/admin", token); rr.Code != http.StatusSeeOther {
+			t.Errorf("expected the %s session to stay signed out, got %d", name, rr.Code)
+		}
+	}
+}
+
+func TestShowPost_DraftsOnlyForTheirEditors(t *testing.T) {
+	owner, err := queries.CreateAdmin("draft-owner@example.com", "hash", models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("error creating admin: %v", err)
+	}
+	other, err := queries.CreateAdmin("draft-other@example.com", "hash", models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("error creating admin: %v", err)
+	}
+	post, err := queries.CreatePost("Private Draft", "tag", "body", "private-draft", "", nil, "", owner.ID)
+	if err != nil {
+		t.Fatalf("error creating post: %v", err)
+	}
+	t.Cleanup(func() {
+		queries.DeletePost(post.ID)
+		queries.DeleteAdmin(owner.ID)
+		queries.DeleteAdmin(other.ID)
+	})
+
+	r := chi.NewRouter()
+	r.Use(sessionManager.LoadAndSave)
+	r.Get("/posts/{slug}", handleShowPost)
+
+	view := func(admin *models.Admin) int {
+		req := httptest.NewRequest("GET", "/posts/"+post.Slug, nil)
+		if admin != nil {
+			ctx, _ := sessionManager.Load(context.Background(), "")
+			sessionManager.Put(ctx, "admin_id", strconv.Itoa(admin.ID))
+			token, _, err := sessionManager.Commit(ctx)
+			if err != nil {
+				t.Fatalf("error saving session: %v", err)
+			}
+			req.AddCookie(&http.Cookie{Name: sessionManager.Cookie.Name, Value: token})
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := view(nil); code != http.StatusNotFound {
+		t.Errorf("expected visitors not to see the draft, got %d", code)
+	}
+	if code := view(&other); code != http.StatusNotFound {
+		t.Errorf("expected another author not to see the draft, got %d", code)
+	}
+	if code := view(&owner); code != http.StatusOK {
+		t.Errorf("expected the author to see their draft, got %d", code)
+	}
+	if err := queries.SetAdminDisabled(owner.ID, true); err != nil {
+		t.Fatalf("error disabling admin: %v", err)
+	}
+	if code := view(&owner); code != http.StatusNotFound {
+		t.Errorf("expected a disabled author not to see the draft, got %d", code)
+	}
+}
+
+func TestRenderTemplate_ErrorStatusConsumesFlash(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(sessionManager.LoadAndSave)
+	r.Get("/flash", func(w http.ResponseWriter, r *http.Request) { setFlash(r, "Shown once.") })
+	r.Get("/form", func(w http.ResponseWriter, r *http.Request) {
+		renderTemplate(w, r, http.StatusUnprocessableEntity, "login", nil)
+	})
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/flash", nil))
+	cookie := rr.Result().Cookies()[0]
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/form", nil)
+		req.AddCookie(cookie)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr = get()
+	if rr.Code != http.StatusUnprocessableEntity || !strings.Contains(rr.Body.String(), "Shown once.") {
+		t.Fatalf("expected the flash on a 422 page, got %d", rr.Code)
+	}
+	if rr := get(); strings.Contains(rr.Body.String(), "Shown once.") {
+		t.Error("expected the flash to be gone after it was shown")
+	}
+}
+
+func TestLoginAttempt_UnknownPasskeysDontShareACount(t *testing.T) {
+	const prefix = "198.51.100."
+	t.Cleanup(func() {
+		database.Pool.Exec(context.Background(), `DELETE FROM login_attempts WHERE ip LIKE $1`, prefix+"%")
+	})
+	attempt := func(n int) loginAttempt {
+		req := httptest.NewRequest("POST", "/login/passkey", nil)
+		req.RemoteAddr = prefix + strconv.Itoa(n) + ":1234"
+		a, err := newLoginAttempt(req, "")
+		if err != nil {
+			t.Fatalf("error recording attempt: %v", err)
+		}
+		return a
+	}
+
+	// Failures from many addresses, none of them enough to lock an address.
+	for n := range lockout.Account.Free + 3 {
+		attempt(n).failed()
+	}
+	if wait := attempt(200).wait(); wait > 0 {
+		t.Errorf("expected a new address not to be throttled by others' unknown passkeys, got %v", wait)
+	}
+}
+
+func TestLogin_ParallelGuessesShareTheThrottle(t *testing.T) {
+	const ip, email = "203.0.113.42", "parallel-guesses@example.com"
+	t.Cleanup(func() {
+		database.Pool.Exec(context.Background(), `DELETE FROM login_attempts WHERE ip = $1`, ip)
+	})
+
+	const guesses = 10
+	codes := make(chan int, guesses)
+	var wg sync.WaitGroup
+	for range guesses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			form := url.Values{"email": {email}, "password": {"not the password"}}
+			req := httptest.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.RemoteAddr = ip + ":1234"
+			rr := httptest.NewRecorder()
+			serve(handleLogin, rr, req)
+			codes <- rr.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	checked := 0
+	for code := range codes {
+		if code != http.StatusTooManyRequests {
+			checked++
+		}
+	}
+	if checked > lockout.Account.Free {
+		t.Errorf("expected at most %d guesses to be checked before the throttle applies, got %d", lockout.Account.Free, checked)
+	}
+}
+
+
 // Helpers
 
+// serve runs h inside the session middleware, as the router does.
+func serve(h http.HandlerFunc, rr *httptest.ResponseRecorder, req *http.Request) {
+	sessionManager.LoadAndSave(h).ServeHTTP(rr, req)
+}
+
 func buildPostForm(t *testing.T, fields map[string]string) (*bytes.Buffer, string) {
 	t.Helper()
 	var buf bytes.Buffer
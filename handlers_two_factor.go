@@ -0,0 +1,156 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/queries"
+	"github.com/hiimtaylorjones/hiimtaylor-go/totp"
+	"github.com/hiimtaylorjones/hiimtaylor-go/validation"
+)
+
+// siteName names this site in authenticator apps and passkey prompts.
+const siteName = "hiimtaylorjones"
+
+// renderTwoFactorSetup shows the enrollment page for the secret being set
+// up, which is kept in the session until the user confirms a code from it.
+func renderTwoFactorSetup(w http.ResponseWriter, r *http.Request, status int, errs validation.Errors) {
+	secret := sessionManager.GetString(r.Context(), "totp_pending_secret")
+	if secret == "" {
+		var err error
+		if secret, err = totp.GenerateSecret(); err != nil {
+			http.Error(w, "Error setting up two-factor", http.StatusInternalServerError)
+			return
+		}
+		sessionManager.Put(r.Context(), "totp_pending_secret", secret)
+	}
+
+	// Groups of four are easier to type into an app by hand.
+	var groups []string
+	for i := 0; i < len(secret); i += 4 {
+		groups = append(groups, secret[i:min(i+4, len(secret))])
+	}
+
+	renderTemplate(w, r, status, "admin.two_factor", map[string]any{
+		"Secret": strings.Join(groups, " "),
+		// html/template would blank an otpauth: link as an unknown scheme.
+		"URI":    template.URL(totp.URI(siteName, currentAdmin(r).Email, secret)),
+		"Errors": errs,
+	})
+}
+
+// renderRecoveryCodes shows newly issued recovery codes. They are stored
+// hashed, so this is the only time the user sees them.
+func renderRecoveryCodes(w http.ResponseWriter, r *http.Request, codes []string) {
+	renderTemplate(w, r, http.StatusOK, "admin.recovery_codes", map[string]any{"Codes": codes})
+}
+
+// newRecoveryCodes returns a fresh set of recovery codes and their hashes.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := totp.RecoveryCodes(totp.RecoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = totp.HashRecoveryCode(c)
+	}
+	return codes, hashes, nil
+}
+
+func handleTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	if currentAdmin(r).TwoFactorEnabled() {
+		http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+		return
+	}
+	renderTwoFactorSetup(w, r, http.StatusOK, nil)
+}
+
+func handleEnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	admin := currentAdmin(r)
+	secret := sessionManager.GetString(r.Context(), "totp_pending_secret")
+	if admin.TwoFactorEnabled() || secret == "" {
+		http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+		return
+	}
+
+	step, ok := totp.Verify(secret, strings.TrimSpace(r.FormValue("code")), time.Now())
+	if !ok {
+		renderTwoFactorSetup(w, r, http.StatusUnprocessableEntity, validation.Errors{
+			"code": "That code didn't match. Check your app is set up with the key above and try the current code.",
+		})
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		http.Error(w, "Error setting up two-factor", http.StatusInternalServerError)
+		return
+	}
+	if err := queries.EnableTwoFactor(admin.ID, secret, step, hashes); err != nil {
+		http.Error(w, "Error setting up two-factor", http.StatusInternalServerError)
+		return
+	}
+	sessionManager.Remove(r.Context(), "totp_pending_secret")
+
+	renderRecoveryCodes(w, r, codes)
+}
+
+func handleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	admin := currentAdmin(r)
+	if !admin.TwoFactorEnabled() {
+		http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+		return
+	}
+	if !checkCurrentPassword(r) {
+		renderSettings(w, r, http.StatusUnprocessableEntity, nil, nil, validation.Errors{
+			"current_password": "That isn't your current password.",
+		})
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		http.Error(w, "Error creating recovery codes", http.StatusInternalServerError)
+		return
+	}
+	if err := queries.ReplaceRecoveryCodes(admin.ID, hashes); err != nil {
+		http.Error(w, "Error creating recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	renderRecoveryCodes(w, r, codes)
+}
+
+func handleDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	if !checkCurrentPassword(r) {
+		renderSettings(w, r, http.StatusUnprocessableEntity, nil, nil, validation.Errors{
+			"current_password": "That isn't your current password.",
+		})
+		return
+	}
+	if err := queries.DisableTwoFactor(currentAdmin(r).ID); err != nil {
+		http.Error(w, "Error turning off two-factor", http.StatusInternalServerError)
+		return
+	}
+
+	setFlash(r, "Two-factor sign in turned off.")
+	http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/hiimtaylorjones/hiimtaylor-go/passwords"
+	"github.com/hiimtaylorjones/hiimtaylor-go/queries"
+	"github.com/hiimtaylorjones/hiimtaylor-go/validation"
+)
+
+// renderSettings shows the account settings page. emailErrs, passwordErrs
+// and twoFactorErrs belong to the forms on it; any may be nil.
+func renderSettings(w http.ResponseWriter, r *http.Request, status int, emailErrs, passwordErrs, twoFactorErrs validation.Errors) {
+	account := currentAdmin(r)
+	passkeys, err := queries.ListPasskeys(account.ID)
+	if err != nil {
+		http.Error(w, "Error loading settings", http.StatusInternalServerError)
+		return
+	}
+	var codesLeft int
+	if account.TwoFactorEnabled() {
+		if codesLeft, err = queries.CountRecoveryCodes(account.ID); err != nil {
+			http.Error(w, "Error loading settings", http.StatusInternalServerError)
+			return
+		}
+	}
+	renderTemplate(w, r, status, "admin.settings", map[string]any{
+		"Account":           account,
+		"EmailErrors":       emailErrs,
+		"PasswordErrors":    passwordErrs,
+		"TwoFactorErrors":   twoFactorErrs,
+		"RecoveryCodesLeft": codesLeft,
+		"Passkeys":          passkeys,
+	})
+}
+
+func handleSettings(w http.ResponseWriter, r *http.Request) {
+	renderSettings(w, r, http.StatusOK, nil, nil, nil)
+}
+
+// checkCurrentPassword reports whether the current_password field matches
+// the signed-in user's password.
+func checkCurrentPassword(r *http.Request) bool {
+	ok, _ := passwords.Verify(currentAdmin(r).EncryptedPassword, r.FormValue("current_password"))
+	return ok
+}
+
+func handleUpdateEmail(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	email := strings.TrimSpace(r.FormValue("email"))
+	errs := validation.Account(email, "", "", false)
+	if email == "" {
+		errs["email"] = "Email is required."
+	}
+	if !checkCurrentPassword(r) {
+		errs["current_password"] = "That isn't your current password."
+	}
+	if !errs.Any() {
+		err := queries.UpdateAdminEmail(currentAdmin(r).ID, email)
+		if errors.Is(err, queries.ErrEmailTaken) {
+			errs["email"] = "Another user already has that email address."
+		} else if err != nil {
+			http.Error(w, "Error updating email", http.StatusInternalServerError)
+			return
+		}
+	}
+	if errs.Any() {
+		renderSettings(w, r, http.StatusUnprocessableEntity, errs, nil, nil)
+		return
+	}
+
+	setFlash(r, "Email updated.")
+	http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+}
+
+func handleUpdatePassword(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	password := r.FormValue("password")
+	errs := validation.Account("", password, r.FormValue("password_confirmation"), false)
+	if password == "" {
+		errs["password"] = "Password is required."
+	}
+	if !checkCurrentPassword(r) {
+		errs["current_password"] = "That isn't your current password."
+	}
+	if errs.Any() {
+		renderSettings(w, r, http.StatusUnprocessableEntity, nil, errs, nil)
+		return
+	}
+
+	hash, err := passwords.Hash(password)
+	if err != nil {
+		http.Error(w, "Error updating password", http.StatusInternalServerError)
+		return
+	}
+	if err := queries.UpdateAdminPassword(currentAdmin(r).ID, hash); err != nil {
+		http.Error(w, "Error updating password", http.StatusInternalServerError)
+		return
+	}
+	// Anyone who had the old password may still be signed in elsewhere.
+	if err := signOutOtherSessions(r, currentAdmin(r).ID); err != nil {
+		log.Printf("could not sign out other sessions: %v", err)
+	}
+
+	setFlash(r, "Password updated. Your other sessions were signed out.")
+	http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+}
+
+// renderUsers shows the user management page. form holds the values of the
+// new user and invite forms when one is re-rendered with errors; errs and
+// inviteErrs belong to those forms.
+func renderUsers(w http.ResponseWriter, r *http.Request, status int, form map[string]string, errs, inviteErrs validation.Errors) {
+	admins, err := queries.ListAdmins()
+	if err != nil {
+		http.Error(w, "Error fetching users", http.StatusInternalServerError)
+		return
+	}
+	invites, err := queries.ListPendingInvites()
+	if err != nil {
+		http.Error(w, "Error fetching invites", http.StatusInternalServerError)
+		return
+	}
+	renderTemplate(w, r, status, "admin.users", map[string]any{
+		"Users":        admins,
+		"Roles":        models.Roles,
+		"Form":         form,
+		"Errors":       errs,
+		"Invites":      invites,
+		"InviteErrors": inviteErrs,
+		// A new invite's link is shown once, right after it's made.
+		"InviteLink": sessionManager.PopString(r.Context(), "invite_link"),
+	})
+}
+
+func handleUsers(w http.ResponseWriter, r *http.Request) {
+	renderUsers(w, r, http.StatusOK, nil, nil, nil)
+}
+
+func handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	email := strings.TrimSpace(r.FormValue("email"))
+	role := models.Role(r.FormValue("role"))
+	password := r.FormValue("password")
+
+	errs := validation.Account(email, password, r.FormValue("password_confirmation"), true)
+	if !role.Valid() {
+		errs["role"] = "Pick a role."
+	}
+	if !errs.Any() {
+		hash, err := passwords.Hash(password)
+		if err != nil {
+			http.Error(w, "Error creating user", http.StatusInternalServerError)
+			return
+		}
+		_, err = queries.CreateAdmin(email, hash, role)
+		if errors.Is(err, queries.ErrEmailTaken) {
+			errs["email"] = "Another user already has that email address."
+		} else if err != nil {
+			http.Error(w, "Error creating user", http.StatusInternalServerError)
+			return
+		}
+	}
+	if errs.Any() {
+		form := map[string]string{"email": email, "role": string(role)}
+		renderUsers(w, r, http.StatusUnprocessableEntity, form, errs, nil)
+		return
+	}
+
+	setFlash(r, "Added "+email+".")
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
+
+// userAction loads the user named by {id} for the role, disable and delete
+// actions. Admins can't use these on their own account, which also keeps at
+// least one active admin around.
+func userAction(w http.ResponseWriter, r *http.Request) (models.Admin, bool) {
+	id, ok := idParam(r)
+	if !ok {
+		http.NotFound(w, r)
+		return models.Admin{}, false
+	}
+	if id == currentAdmin(r).ID {
+		setFlash(r, "You can't change your own role or access here.")
+		http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+		return models.Admin{}, false
+	}
+	admin, err := queries.GetAdminByID(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return models.Admin{}, false
+	}
+	return admin, true
+}
+
+// userActionDone reports the result of a user action and returns to the
+// user list.
+func userActionDone(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, queries.ErrAdminNotFound):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		log.Printf("user action failed: %v", err)
+		http.Error(w, "Error updating user", http.StatusInternalServerError)
+		return
+	}
+	setFlash(r, message)
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
+
+func handleSetUserRole(w http.ResponseWriter, r *http.Request) {
+	admin, ok := userAction(w, r)
+	if !ok {
+		return
+	}
+	role := models.Role(r.FormValue("role"))
+	if !role.Valid() {
+		http.Error(w, "Unknown role", http.StatusBadRequest)
+		return
+	}
+	err := queries.SetAdminRole(admin.ID, role)
+	userActionDone(w, r, err, admin.Email+" is now "+strings.ToLower(role.Label())+".")
+}
+
+func handleDisableUser(w http.ResponseWriter, r *http.Request) {
+	admin, ok := userAction(w, r)
+	if !ok {
+		return
+	}
+	err := queries.SetAdminDisabled(admin.ID, true)
+	userActionDone(w, r, err, admin.Email+" can no longer sign in.")
+}
+
+func handleEnableUser(w http.ResponseWriter, r *http.Request) {
+	admin, ok := userAction(w, r)
+	if !ok {
+		return
+	}
+	err := queries.SetAdminDisabled(admin.ID, false)
+	userActionDone(w, r, err, admin.Email+" can sign in again.")
+}
+
+// handleDeleteUser removes a user. Their posts stay, without an author.
+func handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	admin, ok := userAction(w, r)
+	if !ok {
+		return
+	}
+	err := queries.DeleteAdmin(admin.ID)
+	userActionDone(w, r, err, "Deleted "+admin.Email+".")
+}
+
+// handleSecurity lists recent login lockouts, so admins can spot someone
+// guessing at passwords.
+func handleSecurity(w http.ResponseWriter, r *http.Request) {
+	events, err := queries.GetLockoutEvents(100)
+	if err != nil {
+		http.Error(w, "Error fetching lockouts", http.StatusInternalServerError)
+		return
+	}
+	renderTemplate(w, r, http.StatusOK, "admin.security", map[string]any{"Events": events})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/hiimtaylorjones/hiimtaylor-go/queries"
+	"github.com/jackc/pgx/v5"
+)
+
+// workflowPost loads the post named by {id} for the workflow actions, which
+// all return to its edit page unless a dashboard return_to is given.
+func workflowPost(w http.ResponseWriter, r *http.Request) (models.Post, bool) {
+	id, ok := idParam(r)
+	if !ok {
+		http.NotFound(w, r)
+		return models.Post{}, false
+	}
+	post, err := queries.GetPostByID(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return models.Post{}, false
+	}
+	return post, true
+}
+
+// handleTransitionPost moves a post to the submitted status. publish_at comes
+// from a datetime-local input and is read in the server's time zone.
+func handleTransitionPost(w http.ResponseWriter, r *http.Request) {
+	post, ok := workflowPost(w, r)
+	if !ok {
+		return
+	}
+
+	to := models.PostStatus(r.FormValue("status"))
+	if !currentAdmin(r).CanMove(post, to) {
+		http.Error(w, "You don't have permission to do that.", http.StatusForbidden)
+		return
+	}
+	var publishAt *time.Time
+	if t, err := time.ParseInLocation("2006-01-02T15:04", r.FormValue("publish_at"), time.Local); err == nil {
+		publishAt = &t
+	}
+
+	err := queries.TransitionPost(post.ID, to, publishAt)
+	switch {
+	case errors.Is(err, queries.ErrInvalidTransition):
+		if to == models.StatusScheduled {
+			setFlash(r, "Pick a publish time in the future to schedule this post.")
+		} else {
+			setFlash(r, "A "+strings.ToLower(post.Status.Label())+" post can't be moved to "+strings.ToLower(to.Label())+".")
+		}
+	case err != nil:
+		http.Error(w, "Error updating post", http.StatusInternalServerError)
+		return
+	default:
+		setFlash(r, "Post moved to "+strings.ToLower(to.Label())+".")
+	}
+	redirectBack(w, r, "/posts/"+post.Slug+"/edit")
+}
+
+func handleAssignReviewer(w http.ResponseWriter, r *http.Request) {
+	post, ok := workflowPost(w, r)
+	if !ok {
+		return
+	}
+
+	reviewerID, err := strconv.Atoi(r.FormValue("reviewer_id"))
+	if err != nil {
+		http.Error(w, "Pick a reviewer from the list.", http.StatusUnprocessableEntity)
+		return
+	}
+	// Only users who can approve a post may review it; 0 means nobody.
+	if reviewerID != 0 {
+		reviewer, err := queries.GetAdminByID(reviewerID)
+		if errors.Is(err, pgx.ErrNoRows) || (err == nil && !reviewer.Can(models.PermPublishPosts)) {
+			http.Error(w, "That user can't review posts.", http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error assigning reviewer", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = queries.AssignReviewer(post.ID, reviewerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error assigning reviewer", http.StatusInternalServerError)
+		return
+	}
+
+	if reviewerID == 0 {
+		setFlash(r, "Reviewer removed.")
+	} else {
+		setFlash(r, "Reviewer assigned.")
+	}
+	redirectBack(w, r, "/posts/"+post.Slug+"/edit")
+}
+
+func handleAddReviewComment(w http.ResponseWriter, r *http.Request) {
+	post, ok := workflowPost(w, r)
+	if !ok {
+		return
+	}
+
+	body := strings.TrimSpace(r.FormValue("body"))
+	if body == "" {
+		setFlash(r, "Comment can't be blank.")
+		redirectBack(w, r, "/posts/"+post.Slug+"/edit")
+		return
+	}
+	if _, err := queries.AddReviewComment(post.ID, currentAdminID(r), body); err != nil {
+		http.Error(w, "Error saving comment", http.StatusInternalServerError)
+		return
+	}
+
+	setFlash(r, "Comment added.")
+	redirectBack(w, r, "/posts/"+post.Slug+"/edit#review")
+}
@@ -0,0 +1,67 @@
+// Package lockout decides how long a client must wait before another login
+// attempt, given how many times it has recently failed. It only does the
+// arithmetic; attempts are stored by the queries package.
+package lockout
+
+import "time"
+
+// Policy is an exponential backoff: the first Free failures cost nothing,
+// then each further failure doubles the wait, starting at Base and capped at
+// Max. Reaching Threshold failures counts as a lockout and is worth telling
+// an admin about. Failures older than Window are forgotten.
+type Policy struct {
+	Free      int
+	Base      time.Duration
+	Max       time.Duration
+	Threshold int
+	Window    time.Duration
+}
+
+// Account applies to guesses against one email address. It is strict: ten
+// wrong passwords lock the account for the maximum wait.
+var Account = Policy{
+	Free:      3,
+	Base:      8 * time.Second,
+	Max:       15 * time.Minute,
+	Threshold: 10,
+	Window:    time.Hour,
+}
+
+// IP applies to all guesses from one address. It is looser than Account
+// because offices and phones share addresses, but stops one client from
+// spraying guesses across many accounts.
+var IP = Policy{
+	Free:      10,
+	Base:      time.Second,
+	Max:       15 * time.Minute,
+	Threshold: 30,
+	Window:    time.Hour,
+}
+
+// Delay returns how long to wait after failures consecutive failures.
+func (p Policy) Delay(failures int) time.Duration {
+	if failures < p.Free {
+		return 0
+	}
+	d := p.Base
+	for i := p.Free; i < failures; i++ {
+		d *= 2
+		if d >= p.Max {
+			return p.Max
+		}
+	}
+	return min(d, p.Max)
+}
+
+// RetryAt returns when the next attempt is allowed, given the failure count
+// and when the last failure happened.
+func (p Policy) RetryAt(failures int, lastFailure time.Time) time.Time {
+	return lastFailure.Add(p.Delay(failures))
+}
+
+// LocksOut reports whether failures is the count at which the policy
+// considers the client locked out. It is true only once, at the threshold,
+// so each lockout is reported a single time.
+func (p Policy) LocksOut(failures int) bool {
+	return failures == p.Threshold
+}
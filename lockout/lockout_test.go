@@ -0,0 +1,52 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicy_Delay(t *testing.T) {
+	p := Policy{Free: 3, Base: time.Second, Max: time.Minute, Threshold: 10}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{6, 8 * time.Second},
+		{9, time.Minute},
+		{100, time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := p.Delay(tt.failures); got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy_RetryAt(t *testing.T) {
+	p := Policy{Free: 1, Base: time.Second, Max: time.Minute}
+	last := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if got := p.RetryAt(0, last); !got.Equal(last) {
+		t.Errorf("expected no wait without failures, got %v", got)
+	}
+	if got := p.RetryAt(3, last); !got.Equal(last.Add(4 * time.Second)) {
+		t.Errorf("expected a 4s wait after 3 failures, got %v", got.Sub(last))
+	}
+}
+
+func TestPolicy_LocksOut(t *testing.T) {
+	for n := 0; n < 20; n++ {
+		if got := Account.LocksOut(n); got != (n == Account.Threshold) {
+			t.Errorf("LocksOut(%d) = %v", n, got)
+		}
+	}
+	if Account.Delay(Account.Threshold) != Account.Max {
+		t.Error("expected the account policy to reach its maximum wait by the lockout threshold")
+	}
+}
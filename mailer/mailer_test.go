@@ -0,0 +1,74 @@
+package mailer
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/mailer/mailertest"
+)
+
+func TestSMTP_Send(t *testing.T) {
+	server := mailertest.NewServer(t)
+	m := SMTP{Addr: server.Addr, From: "Site <noreply@example.com>"}
+
+	body := "Hi,\n\nReset your password: https://example.com/login/reset/" + strings.Repeat("x", 80) + "\n\n.\nThanks"
+	if err := m.Send(Message{To: "me@example.com", Subject: "Réinitialiser", Body: body}); err != nil {
+		t.Fatalf("Error sending: %v", err)
+	}
+
+	msgs := server.Wait(1, time.Second)
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(msgs))
+	}
+	msg := msgs[0]
+
+	if got := msg.Header.Get("To"); got != "<me@example.com>" {
+		t.Errorf("unexpected To %q", got)
+	}
+	if got := msg.Header.Get("From"); got != `"Site" <noreply@example.com>` {
+		t.Errorf("unexpected From %q", got)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); subject != "Réinitialiser" {
+		t.Errorf("unexpected Subject %q", subject)
+	}
+
+	decoded, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatalf("Error decoding body: %v", err)
+	}
+	// SMTP ends the data with a line break if the message doesn't.
+	if got := strings.TrimSuffix(strings.ReplaceAll(string(decoded), "\r\n", "\n"), "\n"); got != body {
+		t.Errorf("expected the body to survive the trip, got %q", got)
+	}
+}
+
+func TestSMTP_RejectsHeaderInjection(t *testing.T) {
+	m := SMTP{Addr: "127.0.0.1:1", From: "noreply@example.com"}
+
+	if err := m.Send(Message{To: "me@example.com\r\nBcc: you@example.com", Subject: "Hi"}); err == nil {
+		t.Error("expected a recipient with a line break to be rejected")
+	}
+	if err := m.Send(Message{To: "me@example.com", Subject: "Hi\r\nBcc: you@example.com"}); err == nil {
+		t.Error("expected a subject with a line break to be rejected")
+	}
+}
+
+func TestLog_KeepsBodiesOutOfTheLog(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+
+	Log{}.Send(Message{To: "a@example.com", Subject: "Reset", Body: "https://example.com/login/reset/secret"})
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("expected the body not to be logged, got %q", buf.String())
+	}
+	if Delivers(Log{}) || !Delivers(SMTP{}) {
+		t.Error("expected only the SMTP mailer to deliver")
+	}
+}
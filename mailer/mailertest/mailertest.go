@@ -0,0 +1,126 @@
+// Package mailertest runs a stand-in SMTP server for tests. It accepts every
+// message, over plain SMTP on localhost, and keeps them for inspection.
+package mailertest
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server is a local SMTP server. Point a mailer.SMTP at Addr.
+type Server struct {
+	Addr string
+
+	ln       net.Listener
+	mu       sync.Mutex
+	messages []*mail.Message
+	received chan struct{}
+}
+
+// NewServer starts a server that is shut down when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("mailertest: %v", err)
+	}
+	s := &Server{Addr: ln.Addr().String(), ln: ln, received: make(chan struct{}, 100)}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// Messages returns the messages received so far.
+func (s *Server) Messages() []*mail.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*mail.Message(nil), s.messages...)
+}
+
+// Wait blocks until n messages have arrived or timeout passes, and returns
+// the messages received.
+func (s *Server) Wait(n int, timeout time.Duration) []*mail.Message {
+	deadline := time.After(timeout)
+	for len(s.Messages()) < n {
+		select {
+		case <-s.received:
+		case <-deadline:
+			return s.Messages()
+		}
+	}
+	return s.Messages()
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 mailertest ready")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 mailertest")
+		case strings.HasPrefix(cmd, "MAIL FROM"), strings.HasPrefix(cmd, "RCPT TO"), cmd == "RSET", cmd == "NOOP":
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 end with <CRLF>.<CRLF>")
+			data, err := readData(r)
+			if err != nil {
+				return
+			}
+			msg, err := mail.ReadMessage(bytes.NewReader(data))
+			if err != nil {
+				reply("554 unreadable message")
+				continue
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			s.received <- struct{}{}
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+// readData reads a DATA section up to the terminating "." line, undoing
+// dot-stuffing.
+func readData(r *bufio.Reader) ([]byte, error) {
+	var b bytes.Buffer
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if line == ".\r\n" || line == ".\n" {
+			return b.Bytes(), nil
+		}
+		b.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
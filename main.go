@@ -1,17 +1,29 @@
 package main
 
 import (
+    "bytes"
+    "cmp"
     "html/template"
     "log"
     "time"
     "net/http"
+    "os"
     "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
 
     "github.com/go-chi/chi/v5"
     "github.com/go-chi/chi/v5/middleware"
     "github.com/joho/godotenv"
 
     "github.com/hiimtaylorjones/hiimtaylor-go/database"
+    "github.com/hiimtaylorjones/hiimtaylor-go/mailer"
+    "github.com/hiimtaylorjones/hiimtaylor-go/oidc"
+    "github.com/hiimtaylorjones/hiimtaylor-go/models"
+    "github.com/hiimtaylorjones/hiimtaylor-go/queries"
+    "github.com/hiimtaylorjones/hiimtaylor-go/sessionstore"
+    "github.com/hiimtaylorjones/hiimtaylor-go/uploads"
     authmiddleware "github.com/hiimtaylorjones/hiimtaylor-go/middleware"
     "github.com/alexedwards/scs/v2"
 )
@@ -19,6 +31,22 @@ import (
 var templates map[string]*template.Template
 var sessionManager *scs.SessionManager
 
+// sessionStore is sessionManager's store, kept for finding and ending one
+// admin's sessions.
+var sessionStore *sessionstore.Store
+
+// siteMailer sends email. main configures SMTP from the environment; until
+// then, and in tests that don't replace it, nothing is sent.
+var siteMailer mailer.Mailer = mailer.Log{}
+
+// trashRetention is how long trashed posts are kept before they are purged
+// automatically. Set TRASH_RETENTION_DAYS to override the 30 day default.
+var trashRetention = 30 * 24 * time.Hour
+
+// postLayouts holds the names of the alternate post layouts found under
+// templates/posts/layouts, sorted for display in the editor.
+var postLayouts []string
+
 func loadTemplates() {
     templates = make(map[string]*template.Template)
 
@@ -29,17 +57,56 @@ func loadTemplates() {
         "home":           "templates/home.html",
         "resume":         "templates/resume.html",
         "login":          "templates/login.html",
+        "login.two_factor": "templates/login_two_factor.html",
+        "login.forgot":   "templates/login_forgot.html",
+        "login.reset":    "templates/login_reset.html",
+        "invite.accept":  "templates/invite_accept.html",
         "posts.index":    "templates/posts/index.html",
         "posts.show":     "templates/posts/show.html",
         "posts.new":      "templates/posts/new.html",
         "posts.edit":     "templates/posts/edit.html",
+        "admin.dashboard": "templates/admin/dashboard.html",
+        "admin.bulk":     "templates/admin/bulk.html",
+        "admin.trash":    "templates/admin/trash.html",
+        "admin.profile":  "templates/admin/profile.html",
+        "admin.settings": "templates/admin/settings.html",
+        "admin.users":    "templates/admin/users.html",
+        "admin.security": "templates/admin/security.html",
+        "admin.sessions": "templates/admin/sessions.html",
+        "admin.two_factor": "templates/admin/two_factor.html",
+        "admin.recovery_codes": "templates/admin/recovery_codes.html",
+        "authors.show":   "templates/authors/show.html",
+        "errors.csrf":    "templates/errors/csrf.html",
     }
 
     funcMap := template.FuncMap{
       "add":      func(a, b int) int { return a + b },
       "subtract": func(a, b int) int { return a - b },
+      // Placeholders; renderTemplate binds these to the request's session.
+      "csrfToken": func() string { return "" },
+      "csrfField": func() template.HTML { return "" },
+      // singleSignOn names the identity provider, or is empty when
+      // single sign-on isn't configured.
+      "singleSignOn": func() string {
+        if oidcClient == nil {
+          return ""
+        }
+        return oidcName
+      },
     }
 
+    // Each file in templates/posts/layouts is an alternate "posts.show"
+    // registered as "posts.layout.<name>", e.g. photo-essay.html becomes
+    // "posts.layout.photo-essay".
+    postLayouts = nil
+    layoutFiles, _ := filepath.Glob("templates/posts/layouts/*.html")
+    for _, file := range layoutFiles {
+        name := strings.TrimSuffix(filepath.Base(file), ".html")
+        pages["posts.layout."+name] = file
+        postLayouts = append(postLayouts, name)
+    }
+    sort.Strings(postLayouts)
+
     for name, pages := range pages {
         files := append(layouts, partials...)
         files = append(files, pages)
@@ -48,16 +115,219 @@ func loadTemplates() {
     }
 }
 
-func renderTemplate(w http.ResponseWriter, name string, data any) {
+// postTemplate returns the template used to show a post, falling back to
+// "posts.show" when the post has no layout or its layout no longer exists.
+func postTemplate(layout string) string {
+    if layout == "" {
+        return "posts.show"
+    }
+    name := "posts.layout." + layout
+    if _, ok := templates[name]; !ok {
+        return "posts.show"
+    }
+    return name
+}
+
+// renderTemplate executes the named page inside the base layout and sends
+// it with status. Pages are given a map of data; renderTemplate adds the
+// pending flash message to it as "Flash" so the layout can show it, and on
+// signed-in pages the user as "CurrentAdmin" so templates can hide actions
+// they aren't allowed. Callers must not write the status themselves: the
+// session is saved when the response starts, so anything taken from it
+// while rendering would be lost.
+func renderTemplate(w http.ResponseWriter, r *http.Request, status int, name string, data map[string]any) {
     tmpl, ok := templates[name]
     if !ok {
         http.Error(w, "Template not found", http.StatusInternalServerError)
         return
     }
-    err := tmpl.ExecuteTemplate(w, "base", data)
+    // Templates are cloned per request so csrfField and csrfToken can see
+    // this request's session. The originals are never executed, which
+    // Clone requires.
+    tmpl, err := tmpl.Clone()
     if err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    // The token is only fetched when a page actually uses it, so public
+    // pages without forms don't start a session for every visitor.
+    var token string
+    csrfToken := func() string {
+        if token == "" {
+            token = authmiddleware.CSRFToken(r.Context())
+        }
+        return token
+    }
+    tmpl.Funcs(template.FuncMap{
+        "csrfToken": csrfToken,
+        "csrfField": func() template.HTML {
+            return template.HTML(`<input type="hidden" name="` + authmiddleware.CSRFFieldName +
+                `" value="` + template.HTMLEscapeString(csrfToken()) + `">`)
+        },
+    })
+    if data == nil {
+        data = map[string]any{}
+    }
+    data["Flash"] = popFlash(r)
+    if admin, ok := authmiddleware.CurrentAdmin(r.Context()); ok {
+        data["CurrentAdmin"] = admin
     }
+    // The page is rendered into a buffer first: the session is saved when
+    // the response starts, and rendering may still create the CSRF token.
+    var buf bytes.Buffer
+    err = tmpl.ExecuteTemplate(&buf, "base", data)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    w.WriteHeader(status)
+    buf.WriteTo(w)
+}
+
+// staticHeaders keeps uploaded files from acting as pages on this origin:
+// browsers must not guess a type other than the one served, and anything
+// but an image is downloaded rather than displayed.
+func staticHeaders(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("X-Content-Type-Options", "nosniff")
+        if strings.HasPrefix(r.URL.Path, "uploads/") && !uploads.Inline(r.URL.Path) {
+            w.Header().Set("Content-Disposition", "attachment")
+        }
+        next.ServeHTTP(w, r)
+    })
+}
+
+// startTrashPurger permanently deletes posts that have outlived
+// trashRetention, once at startup and then hourly.
+func startTrashPurger() {
+    purge := func() {
+        n, files, err := queries.PurgeTrashedOlderThan(trashRetention)
+        if err != nil {
+            log.Printf("trash purge failed: %v", err)
+            return
+        }
+        removeUploads(files)
+        if n > 0 {
+            log.Printf("purged %d post(s) from trash", n)
+        }
+    }
+
+    go func() {
+        purge()
+        for range time.Tick(time.Hour) {
+            purge()
+        }
+    }()
+}
+
+// loginAttemptRetention is how long login attempts are kept. Throttling only
+// looks back an hour; the rest is for investigating lockouts.
+const loginAttemptRetention = 7 * 24 * time.Hour
+
+// startLoginAttemptPruner deletes old login attempts, once at startup and
+// then hourly.
+func startLoginAttemptPruner() {
+    prune := func() {
+        if _, err := queries.PruneLoginAttempts(loginAttemptRetention); err != nil {
+            log.Printf("login attempt pruning failed: %v", err)
+        }
+    }
+
+    go func() {
+        prune()
+        for range time.Tick(time.Hour) {
+            prune()
+        }
+    }()
+}
+
+// configureSessions sets session lifetimes and cookie attributes from the
+// environment:
+//
+//	SESSION_LIFETIME         longest a session lasts, e.g. "6h" (default 6h)
+//	SESSION_IDLE_TIMEOUT     sign out after this long unused (default 1h)
+//	SESSION_COOKIE_SECURE    "true" or "false"; defaults to true when
+//	                         SITE_URL is https
+//	SESSION_COOKIE_SAMESITE  "lax", "strict" or "none" (default lax)
+//
+// The cookie is always HttpOnly. Bad values stop the server rather than
+// silently weakening sessions.
+func configureSessions(sm *scs.SessionManager) {
+    sm.Lifetime = envDuration("SESSION_LIFETIME", 6*time.Hour)
+    sm.IdleTimeout = envDuration("SESSION_IDLE_TIMEOUT", time.Hour)
+    sm.Cookie.HttpOnly = true
+
+    sm.Cookie.Secure = strings.HasPrefix(os.Getenv("SITE_URL"), "https://")
+    if v := os.Getenv("SESSION_COOKIE_SECURE"); v != "" {
+        secure, err := strconv.ParseBool(v)
+        if err != nil {
+            log.Fatalf("SESSION_COOKIE_SECURE: %v", err)
+        }
+        sm.Cookie.Secure = secure
+    }
+
+    switch v := strings.ToLower(os.Getenv("SESSION_COOKIE_SAMESITE")); v {
+    case "", "lax":
+        sm.Cookie.SameSite = http.SameSiteLaxMode
+    case "strict":
+        sm.Cookie.SameSite = http.SameSiteStrictMode
+    case "none":
+        if !sm.Cookie.Secure {
+            log.Fatal("SESSION_COOKIE_SAMESITE=none needs a secure cookie")
+        }
+        sm.Cookie.SameSite = http.SameSiteNoneMode
+    default:
+        log.Fatalf("SESSION_COOKIE_SAMESITE: unknown value %q", v)
+    }
+}
+
+// configureOIDC turns on signing in with an OpenID Connect provider when
+// OIDC_ISSUER is set:
+//
+//	OIDC_ISSUER         the provider's issuer URL, exactly as its tokens give
+//	                    it, trailing slash and all
+//	OIDC_CLIENT_ID      this site's client ID at the provider
+//	OIDC_CLIENT_SECRET  its client secret
+//	OIDC_NAME           what the login button calls the provider
+//	                    (default "single sign-on")
+//
+// Register SITE_URL + "/login/oidc/callback" as the redirect URI. Users are
+// matched to existing accounts by verified email; none are created. Accounts
+// with two-factor sign-in on are still asked for their code.
+func configureOIDC() {
+    issuer := os.Getenv("OIDC_ISSUER")
+    if issuer == "" {
+        return
+    }
+    clientID := os.Getenv("OIDC_CLIENT_ID")
+    if clientID == "" {
+        log.Fatal("OIDC_CLIENT_ID must be set when OIDC_ISSUER is")
+    }
+    oidcClient = oidc.New(issuer, clientID, os.Getenv("OIDC_CLIENT_SECRET"))
+    oidcName = cmp.Or(os.Getenv("OIDC_NAME"), "single sign-on")
+}
+
+// envDuration reads a duration such as "90m" from the environment.
+func envDuration(key string, fallback time.Duration) time.Duration {
+    v := os.Getenv(key)
+    if v == "" {
+        return fallback
+    }
+    d, err := time.ParseDuration(v)
+    if err != nil || d <= 0 {
+        log.Fatalf("%s: expected a positive duration such as 30m, got %q", key, v)
+    }
+    return d
+}
+
+// setFlash stores a one-time message, such as "Post updated", to show on
+// the next rendered page.
+func setFlash(r *http.Request, message string) {
+    sessionManager.Put(r.Context(), "flash", message)
+}
+
+func popFlash(r *http.Request) string {
+    return sessionManager.PopString(r.Context(), "flash")
 }
 
 func main() {
@@ -67,29 +337,66 @@ func main() {
 
     loadTemplates()
 
+    if days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS")); err == nil && days > 0 {
+        trashRetention = time.Duration(days) * 24 * time.Hour
+    }
+    startTrashPurger()
+    startLoginAttemptPruner()
+    siteMailer = mailer.FromEnv()
+    // Reset and invite mail carry links, which only ever come from SITE_URL.
+    if mailer.Delivers(siteMailer) && os.Getenv("SITE_URL") == "" {
+        log.Fatal("SITE_URL must be set when SMTP_HOST is, so emailed reset and invite links point at this site")
+    }
+
     sessionManager = scs.New()
-    sessionManager.Lifetime = 6 * time.Hour
+    // Sessions live in Postgres so sign-ins survive restarts and deploys.
+    sessionStore = sessionstore.New(database.Pool, 5*time.Minute)
+    sessionManager.Store = sessionStore
+    configureSessions(sessionManager)
+    configureOIDC()
     authmiddleware.SetSessionManager(sessionManager)
 
     r := chi.NewRouter()
 
     // Middleware
+    if os.Getenv("TRUST_PROXY_HEADERS") == "true" {
+        // Behind a reverse proxy every request comes from the proxy, which
+        // would make login throttling lock out everyone at once.
+        r.Use(middleware.RealIP)
+    }
     r.Use(middleware.Logger)
     r.Use(middleware.Recoverer)
+    authmiddleware.SetCSRFFailureHandler(http.HandlerFunc(handleCSRFFailure))
+    r.Use(authmiddleware.VerifyCSRF)
 
     // Fetch and Serve Static Files
     fileServer := http.FileServer(http.Dir("static"))
-    r.Handle("/static/*", http.StripPrefix("/static/", fileServer))
+    r.Handle("/static/*", http.StripPrefix("/static/", staticHeaders(fileServer)))
 
     // Public routes
     r.Get("/", handleHome)
     r.Get("/posts", handleListPosts)
+    r.Get("/posts/feed.xml", handleFeed)
+    r.Get("/tags/{tag}/feed.xml", handleTagFeed)
     r.Get("/posts/{slug}", handleShowPost)
     r.Get("/resume", handleResume)
+    r.Get("/authors/{slug}", handleShowAuthor)
 
     // Auth routes
     r.Get("/login", handleLoginForm)
     r.Post("/login", handleLogin)
+    r.Get("/login/two-factor", handleTwoFactorForm)
+    r.Post("/login/two-factor", handleTwoFactor)
+    r.Post("/login/passkey/options", handlePasskeyLoginOptions)
+    r.Post("/login/passkey", handlePasskeyLogin)
+    r.Get("/login/oidc", handleOIDCLogin)
+    r.Get("/login/oidc/callback", handleOIDCCallback)
+    r.Get("/login/forgot", handleForgotPasswordForm)
+    r.Post("/login/forgot", handleForgotPassword)
+    r.Get("/login/reset/{token}", handleResetPasswordForm)
+    r.Post("/login/reset/{token}", handleResetPassword)
+    r.Get("/invites/{token}", handleAcceptInviteForm)
+    r.Post("/invites/{token}", handleAcceptInvite)
     r.Post("/logout", handleLogout)
 
     // Protected routes
@@ -97,9 +404,58 @@ func main() {
         r.Use(authmiddleware.RequireAdmin)
         r.Get("/posts/new", handleNewPost)
         r.Post("/posts", handleCreatePost)
-        r.Get("/posts/{slug}/edit", handleEditPost)
-        r.Post("/posts/{slug}/edit", handleUpdatePost)
-        r.Post("/posts/{slug}/delete", handleDeletePost)
+        r.Get("/admin", handleDashboard)
+        r.Post("/admin/posts/{id}/transition", handleTransitionPost)
+        r.Post("/admin/preview", handlePreview)
+        r.Post("/admin/autosave", handleAutosave)
+        r.Post("/admin/autosave/discard", handleDiscardAutosave)
+        r.Get("/admin/profile", handleProfile)
+        r.Post("/admin/profile", handleUpdateProfile)
+        r.Get("/admin/settings", handleSettings)
+        r.Post("/admin/settings/email", handleUpdateEmail)
+        r.Post("/admin/settings/password", handleUpdatePassword)
+        r.Get("/admin/settings/two-factor", handleTwoFactorSetup)
+        r.Post("/admin/settings/two-factor", handleEnableTwoFactor)
+        r.Post("/admin/settings/two-factor/recovery-codes", handleRegenerateRecoveryCodes)
+        r.Post("/admin/settings/two-factor/disable", handleDisableTwoFactor)
+        r.Post("/admin/settings/passkeys/options", handlePasskeyOptions)
+        r.Post("/admin/settings/passkeys", handleCreatePasskey)
+        r.Post("/admin/settings/passkeys/{id}/delete", handleDeletePasskey)
+        r.Get("/admin/sessions", handleSessions)
+        r.Post("/admin/sessions/delete-all", handleSignOutEverywhere)
+        r.Post("/admin/sessions/{sid}/delete", handleDeleteSession)
+
+        // Authors may only touch their own posts.
+        r.Group(func(r chi.Router) {
+            r.Use(authmiddleware.RequirePostAccess)
+            r.Get("/posts/{slug}/edit", handleEditPost)
+            r.Post("/posts/{slug}/edit", handleUpdatePost)
+            r.Post("/posts/{slug}/delete", handleDeletePost)
+            r.Post("/admin/posts/{id}/reviewer", handleAssignReviewer)
+            r.Post("/admin/posts/{id}/comments", handleAddReviewComment)
+            r.Post("/admin/posts/{id}/duplicate", handleDuplicatePost)
+        })
+
+        r.Group(func(r chi.Router) {
+            r.Use(authmiddleware.RequirePermission(models.PermEditAnyPost))
+            r.Post("/admin/posts/bulk", handleBulkPosts)
+            r.Get("/admin/trash", handleTrash)
+            r.Post("/admin/trash/{id}/restore", handleRestorePost)
+            r.Post("/admin/trash/{id}/purge", handlePurgePost)
+        })
+
+        r.Group(func(r chi.Router) {
+            r.Use(authmiddleware.RequirePermission(models.PermManageUsers))
+            r.Get("/admin/users", handleUsers)
+            r.Post("/admin/users", handleCreateUser)
+            r.Post("/admin/users/{id}/role", handleSetUserRole)
+            r.Post("/admin/users/{id}/disable", handleDisableUser)
+            r.Post("/admin/users/{id}/enable", handleEnableUser)
+            r.Post("/admin/users/{id}/delete", handleDeleteUser)
+            r.Post("/admin/users/invites", handleCreateInvite)
+            r.Post("/admin/users/invites/{id}/revoke", handleRevokeInvite)
+            r.Get("/admin/security", handleSecurity)
+        })
     })
 
 
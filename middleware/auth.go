@@ -1,9 +1,17 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/hiimtaylorjones/hiimtaylor-go/queries"
 )
 
 var sessionManager *scs.SessionManager
@@ -12,13 +20,106 @@ func SetSessionManager(sm *scs.SessionManager) {
 	sessionManager = sm
 }
 
+// getPostBySlug and getPostByID load the post RequirePostAccess checks. They
+// are variables so tests can run without a database.
+var (
+	getPostBySlug = queries.GetPostBySlug
+	getPostByID   = queries.GetPostByID
+)
+
+type contextKey string
+
+const adminKey contextKey = "admin"
+
+// CurrentAdmin returns the signed-in user loaded by RequireAdmin.
+func CurrentAdmin(ctx context.Context) (models.Admin, bool) {
+	admin, ok := ctx.Value(adminKey).(models.Admin)
+	return admin, ok
+}
+
+// RequireAdmin sends visitors without a session to /login and loads the
+// signed-in user into the request context for CurrentAdmin. A session whose
+// user has since been removed or disabled is treated as signed out.
 func RequireAdmin(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		adminID := sessionManager.GetString(r.Context(), "admin_id")
-		if adminID == "" {
+		admin, ok := SignedInAdmin(r)
+		if !ok {
+			sessionManager.Remove(r.Context(), "admin_id")
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
+		ctx := context.WithValue(r.Context(), adminKey, admin)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// SignedInAdmin loads the user signed in to r's session the way RequireAdmin
+// does, for public pages that show more to some users. A user who has been
+// removed or disabled counts as signed out.
+func SignedInAdmin(r *http.Request) (models.Admin, bool) {
+	if admin, ok := CurrentAdmin(r.Context()); ok {
+		return admin, true
+	}
+	adminID, err := strconv.Atoi(sessionManager.GetString(r.Context(), "admin_id"))
+	if err != nil {
+		return models.Admin{}, false
+	}
+	admin, err := queries.GetAdminByID(adminID)
+	if err != nil || admin.Disabled() {
+		return models.Admin{}, false
+	}
+	return admin, true
+}
+
+// RequirePermission only lets through users whose role grants p. It must run
+// after RequireAdmin.
+func RequirePermission(p models.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			admin, ok := CurrentAdmin(r.Context())
+			if !ok || !admin.Can(p) {
+				http.Error(w, "You don't have permission to do that.", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePostAccess guards routes naming a post by {slug} or {id}, only
+// letting through users who may edit it (see models.Admin.CanEdit). Unknown
+// posts are passed on so the handler can answer 404; any other error loading
+// the post stops the request. It must run after RequireAdmin.
+func RequirePostAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		admin, ok := CurrentAdmin(r.Context())
+		if !ok {
+			http.Error(w, "You don't have permission to do that.", http.StatusForbidden)
+			return
+		}
+
+		var (
+			post models.Post
+			err  error
+		)
+		if slug := chi.URLParam(r, "slug"); slug != "" {
+			post, err = getPostBySlug(slug)
+		} else {
+			id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+			post, err = getPostByID(id)
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error loading post", http.StatusInternalServerError)
+			return
+		}
+		if !admin.CanEdit(post) {
+			http.Error(w, "You can only change your own posts.", http.StatusForbidden)
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+// fakePosts replaces the post lookups with posts, keyed by ID, for the
+// length of the test. Slugs are "post-<id>".
+func fakePosts(t *testing.T, posts ...models.Post) {
+	bySlug, byID := getPostBySlug, getPostByID
+	t.Cleanup(func() { getPostBySlug, getPostByID = bySlug, byID })
+
+	find := func(match func(models.Post) bool) (models.Post, error) {
+		for _, p := range posts {
+			if match(p) {
+				return p, nil
+			}
+		}
+		return models.Post{}, fmt.Errorf("post not found: %w", pgx.ErrNoRows)
+	}
+	getPostBySlug = func(slug string) (models.Post, error) {
+		return find(func(p models.Post) bool { return p.Slug == slug })
+	}
+	getPostByID = func(id int) (models.Post, error) {
+		return find(func(p models.Post) bool { return p.ID == id })
+	}
+}
+
+// postRoutes mirrors the post routes in main.go, answering 200 to anything
+// the middleware lets through, with admin signed in in place of
+// RequireAdmin.
+func postRoutes(admin models.Admin) http.Handler {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), adminKey, admin)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(RequirePostAccess)
+		r.Get("/posts/{slug}/edit", ok)
+		r.Post("/posts/{slug}/delete", ok)
+		r.Post("/admin/posts/{id}/comments", ok)
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(RequirePermission(models.PermEditAnyPost))
+		r.Post("/admin/posts/bulk", ok)
+		r.Get("/admin/trash", ok)
+		r.Post("/admin/trash/{id}/purge", ok)
+	})
+	return r
+}
+
+func TestPostAccess(t *testing.T) {
+	author := models.Admin{ID: 1, Role: models.RoleAuthor}
+	editor := models.Admin{ID: 3, Role: models.RoleEditor}
+	fakePosts(t,
+		models.Post{ID: 10, Slug: "post-10", AuthorID: author.ID},
+		models.Post{ID: 20, Slug: "post-20", AuthorID: 2},
+	)
+
+	tests := []struct {
+		name   string
+		admin  models.Admin
+		method string
+		path   string
+		want   int
+	}{
+		{"author edits own post", author, "GET", "/posts/post-10/edit", http.StatusOK},
+		{"author comments on own post", author, "POST", "/admin/posts/10/comments", http.StatusOK},
+		{"author edits another's post", author, "GET", "/posts/post-20/edit", http.StatusForbidden},
+		{"author deletes another's post", author, "POST", "/posts/post-20/delete", http.StatusForbidden},
+		{"author comments on another's post", author, "POST", "/admin/posts/20/comments", http.StatusForbidden},
+		{"author bulk edits", author, "POST", "/admin/posts/bulk", http.StatusForbidden},
+		{"author opens trash", author, "GET", "/admin/trash", http.StatusForbidden},
+		{"author purges", author, "POST", "/admin/trash/20/purge", http.StatusForbidden},
+		{"unknown post is left to the handler", author, "GET", "/posts/missing/edit", http.StatusOK},
+		{"editor edits another's post", editor, "GET", "/posts/post-20/edit", http.StatusOK},
+		{"editor bulk edits", editor, "POST", "/admin/posts/bulk", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			postRoutes(tt.admin).ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+			if rr.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rr.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequirePostAccess_FailsClosedOnLookupError(t *testing.T) {
+	bySlug := getPostBySlug
+	t.Cleanup(func() { getPostBySlug = bySlug })
+	getPostBySlug = func(string) (models.Post, error) {
+		return models.Post{}, errors.New("connection refused")
+	}
+
+	rr := httptest.NewRecorder()
+	admin := models.Admin{ID: 1, Role: models.RoleAuthor}
+	postRoutes(admin).ServeHTTP(rr, httptest.NewRequest("GET", "/posts/post-20/edit", nil))
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("got %d, want %d", rr.Code, http.StatusInternalServerError)
+	}
+}
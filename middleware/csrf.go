@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"mime"
+	"net/http"
+)
+
+// CSRFFieldName is the hidden form field, and CSRFHeaderName the request
+// header used by scripts, that carry the CSRF token.
+const (
+	CSRFFieldName  = "csrf_token"
+	CSRFHeaderName = "X-CSRF-Token"
+)
+
+// MaxRequestBytes caps the body of every state-changing request, and
+// FormMemoryBytes is how much of a multipart form is held in memory before
+// uploads spill to temporary files.
+const (
+	MaxRequestBytes = 100 << 20
+	FormMemoryBytes = 10 << 20
+)
+
+const csrfSessionKey = "csrf_token"
+
+var csrfFailureHandler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "Invalid or missing CSRF token", http.StatusForbidden)
+})
+
+// SetCSRFFailureHandler replaces the plain-text 403 sent when VerifyCSRF
+// rejects a request. The handler should respond with 403 itself.
+func SetCSRFFailureHandler(h http.Handler) {
+	csrfFailureHandler = h
+}
+
+// CSRFToken returns the session's CSRF token, creating one the first time it
+// is needed. Every form in a session shares the token.
+func CSRFToken(ctx context.Context) string {
+	if token := sessionManager.GetString(ctx, csrfSessionKey); token != "" {
+		return token
+	}
+	b := make([]byte, 32)
+	rand.Read(b)
+	token := base64.RawURLEncoding.EncodeToString(b)
+	sessionManager.Put(ctx, csrfSessionKey, token)
+	return token
+}
+
+// VerifyCSRF rejects state-changing requests (anything but GET, HEAD,
+// OPTIONS and TRACE) whose csrf_token field or X-CSRF-Token header doesn't
+// match the session's token. It needs the session loaded, so it must run
+// inside the session manager's LoadAndSave.
+func VerifyCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBytes)
+		expected := sessionManager.GetString(r.Context(), csrfSessionKey)
+		sent := r.Header.Get(CSRFHeaderName)
+		if sent == "" {
+			// The form is parsed here, with the same memory limit handlers
+			// would use, so uploads don't end up held in memory.
+			if err := parseForm(w, r); err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					http.Error(w, "Request too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, "Error parsing form", http.StatusBadRequest)
+				return
+			}
+			sent = r.PostFormValue(CSRFFieldName)
+		}
+		if expected == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(expected)) != 1 {
+			csrfFailureHandler.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// parseForm parses a multipart or URL-encoded body. Other bodies, such as
+// JSON, are left unread for the handler. URL-encoded forms are read wholly
+// into memory, so they get the smaller in-memory limit.
+func parseForm(w http.ResponseWriter, r *http.Request) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data":
+		return r.ParseMultipartForm(FormMemoryBytes)
+	case "application/x-www-form-urlencoded":
+		r.Body = http.MaxBytesReader(w, r.Body, FormMemoryBytes)
+		return r.ParseForm()
+	}
+	return nil
+}
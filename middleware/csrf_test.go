@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// csrfServer returns a handler that issues the session's token on GET /token
+// and answers 200 to any request VerifyCSRF lets through.
+func csrfServer() http.Handler {
+	sm := scs.New()
+	SetSessionManager(sm)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(CSRFToken(r.Context())))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	return sm.LoadAndSave(VerifyCSRF(mux))
+}
+
+// session fetches a token and returns it with the session cookie it belongs
+// to.
+func session(t *testing.T, h http.Handler) (string, *http.Cookie) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/token", nil))
+	cookies := rr.Result().Cookies()
+	if rr.Code != http.StatusOK || len(cookies) == 0 {
+		t.Fatalf("expected a token and session cookie, got %d with %d cookies", rr.Code, len(cookies))
+	}
+	return rr.Body.String(), cookies[0]
+}
+
+func TestVerifyCSRF(t *testing.T) {
+	h := csrfServer()
+	token, cookie := session(t, h)
+	_, otherCookie := session(t, h)
+
+	form := func(token string) *http.Request {
+		body := url.Values{CSRFFieldName: {token}}.Encode()
+		req := httptest.NewRequest("POST", "/posts", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		cookie *http.Cookie
+		want   int
+	}{
+		{"safe method", httptest.NewRequest("GET", "/posts", nil), nil, http.StatusOK},
+		{"form field", form(token), cookie, http.StatusOK},
+		{"missing token", form(""), cookie, http.StatusForbidden},
+		{"wrong token", form(token + "x"), cookie, http.StatusForbidden},
+		{"another session's token", form(token), otherCookie, http.StatusForbidden},
+		{"no session", form(token), nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cookie != nil {
+				tt.req.AddCookie(tt.cookie)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, tt.req)
+			if rr.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, rr.Code)
+			}
+		})
+	}
+}
+
+func TestVerifyCSRF_Header(t *testing.T) {
+	h := csrfServer()
+	token, cookie := session(t, h)
+
+	req := httptest.NewRequest("POST", "/admin/autosave", strings.NewReader(`{"title":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(CSRFHeaderName, token)
+	req.AddCookie(cookie)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected the header token to be accepted, got %d", rr.Code)
+	}
+}
+
+func TestVerifyCSRF_Multipart(t *testing.T) {
+	h := csrfServer()
+	token, cookie := session(t, h)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField(CSRFFieldName, token)
+	mw.Close()
+	req := httptest.NewRequest("POST", "/posts", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.AddCookie(cookie)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected the multipart token to be accepted, got %d", rr.Code)
+	}
+}
+
+func TestVerifyCSRF_TooLarge(t *testing.T) {
+	h := csrfServer()
+	token, cookie := session(t, h)
+
+	body := url.Values{CSRFFieldName: {token}, "body": {strings.Repeat("x", FormMemoryBytes)}}.Encode()
+	req := httptest.NewRequest("POST", "/posts", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for an oversized body, got %d", rr.Code)
+	}
+}
+
+func TestVerifyCSRF_FailureHandler(t *testing.T) {
+	h := csrfServer()
+	original := csrfFailureHandler
+	defer SetCSRFFailureHandler(original)
+	SetCSRFFailureHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("form expired"))
+	}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("POST", "/logout", nil))
+
+	if rr.Code != http.StatusForbidden || rr.Body.String() != "form expired" {
+		t.Errorf("expected the custom rejection page, got %d %q", rr.Code, rr.Body.String())
+	}
+}
@@ -6,6 +6,67 @@ type Admin struct {
 			ID                int
 			Email             string
 			EncryptedPassword string
+			Role              Role
+			// Name, Slug, Bio and AvatarURL make up the public author page at
+			// /authors/{slug}. Slug is empty until the profile is filled in.
+			Name              string
+			Slug              string
+			Bio               string
+			AvatarURL         string
+			// DisabledAt is set while the account is disabled; disabled users
+			// can't sign in but keep their posts and author page.
+			DisabledAt        *time.Time
+			// TOTPSecret is the authenticator secret, set once two-factor
+			// sign-in is turned on at TOTPEnabledAt.
+			TOTPSecret        string
+			TOTPEnabledAt     *time.Time
 			CreatedAt         time.Time
 			UpdatedAt         time.Time
-}
\ No newline at end of file
+}
+
+func (a Admin) Disabled() bool {
+	return a.DisabledAt != nil
+}
+
+// TwoFactorEnabled reports whether signing in needs an authenticator code
+// after the password.
+func (a Admin) TwoFactorEnabled() bool {
+	return a.TOTPEnabledAt != nil
+}
+
+// DisplayName is the name shown on bylines, falling back to the email
+// address for users who haven't filled in their profile.
+func (a Admin) DisplayName() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return a.Email
+}
+
+func (a Admin) Can(p Permission) bool {
+	return a.Role.Can(p)
+}
+
+// CanEdit reports whether a may edit post: editors and admins may edit any
+// post, authors only their own.
+func (a Admin) CanEdit(post Post) bool {
+	if a.Can(PermEditAnyPost) {
+		return true
+	}
+	return a.Can(PermWritePosts) && post.AuthorID == a.ID
+}
+
+// CanMove reports whether a may move post to status to. The workflow itself
+// is checked by CanTransition; this only decides who may ask. Anyone who can
+// edit a post may send it for review or back to draft, but approving,
+// scheduling and publishing, or undoing any of those, needs PermPublishPosts.
+func (a Admin) CanMove(post Post, to PostStatus) bool {
+	if !a.CanEdit(post) {
+		return false
+	}
+	if a.Can(PermPublishPosts) {
+		return true
+	}
+	early := func(s PostStatus) bool { return s == StatusDraft || s == StatusInReview }
+	return early(post.Status) && early(to)
+}
@@ -0,0 +1,49 @@
+package models
+
+import "testing"
+
+func TestAdmin_CanEdit(t *testing.T) {
+	author := Admin{ID: 1, Role: RoleAuthor}
+	editor := Admin{ID: 2, Role: RoleEditor}
+	own := Post{AuthorID: 1}
+	other := Post{AuthorID: 3}
+
+	if !author.CanEdit(own) {
+		t.Error("expected an author to edit their own post")
+	}
+	if author.CanEdit(other) {
+		t.Error("expected an author not to edit someone else's post")
+	}
+	if !editor.CanEdit(other) {
+		t.Error("expected an editor to edit any post")
+	}
+	if (Admin{ID: 1}).CanEdit(own) {
+		t.Error("expected a user without a role to edit nothing")
+	}
+}
+
+func TestAdmin_CanMove(t *testing.T) {
+	author := Admin{ID: 1, Role: RoleAuthor}
+	editor := Admin{ID: 2, Role: RoleEditor}
+
+	tests := []struct {
+		who  Admin
+		from PostStatus
+		to   PostStatus
+		want bool
+	}{
+		{author, StatusDraft, StatusInReview, true},
+		{author, StatusInReview, StatusDraft, true},
+		{author, StatusInReview, StatusApproved, false},
+		{author, StatusPublished, StatusDraft, false},
+		{editor, StatusInReview, StatusApproved, true},
+		{editor, StatusPublished, StatusDraft, true},
+	}
+
+	for _, tt := range tests {
+		post := Post{AuthorID: 1, Status: tt.from}
+		if got := tt.who.CanMove(post, tt.to); got != tt.want {
+			t.Errorf("%s moving %s to %s = %v, want %v", tt.who.Role, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Attachment is a downloadable file (PDF, slides, audio) attached to a post.
+type Attachment struct {
+	ID          int
+	PostID      int
+	URL         string
+	Filename    string
+	ContentType string
+	SizeBytes   int64
+	Position    int
+	CreatedAt   time.Time
+}
+
+func (a Attachment) IsAudio() bool {
+	return strings.HasPrefix(a.ContentType, "audio/")
+}
+
+// HumanSize formats SizeBytes for display, e.g. "512 B", "1.4 MB".
+func (a Attachment) HumanSize() string {
+	const unit = 1024
+	if a.SizeBytes < unit {
+		return fmt.Sprintf("%d B", a.SizeBytes)
+	}
+	div, exp := int64(unit), 0
+	for n := a.SizeBytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(a.SizeBytes)/float64(div), "KMGT"[exp])
+}
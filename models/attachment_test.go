@@ -0,0 +1,31 @@
+package models
+
+import "testing"
+
+func TestAttachment_HumanSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{size: 0, want: "0 B"},
+		{size: 512, want: "512 B"},
+		{size: 1536, want: "1.5 KB"},
+		{size: 5 * 1024 * 1024, want: "5.0 MB"},
+	}
+
+	for _, tt := range tests {
+		a := Attachment{SizeBytes: tt.size}
+		if got := a.HumanSize(); got != tt.want {
+			t.Errorf("HumanSize(%d) = %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestAttachment_IsAudio(t *testing.T) {
+	if !(Attachment{ContentType: "audio/mpeg"}).IsAudio() {
+		t.Error("expected audio/mpeg to be audio")
+	}
+	if (Attachment{ContentType: "application/pdf"}).IsAudio() {
+		t.Error("expected application/pdf not to be audio")
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Invite is a single-use link that lets someone create their own account
+// with Role. If Email is set, the account must use it. InvitedBy and
+// InvitedByEmail are empty once the inviter's account is deleted.
+type Invite struct {
+	ID              int
+	Email           string
+	Role            Role
+	InvitedBy       *int
+	InvitedByEmail  string
+	ExpiresAt       time.Time
+	AcceptedAt      *time.Time
+	AcceptedAdminID *int
+	CreatedAt       time.Time
+}
+
+// Pending reports whether the invite can still be used.
+func (i Invite) Pending() bool {
+	return i.AcceptedAt == nil && time.Now().Before(i.ExpiresAt)
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// LockoutEvent records a client or account being locked out after too many
+// failed logins. Scope is "account" when the guesses targeted one email and
+// "ip" when they came from one address.
+type LockoutEvent struct {
+	ID          int
+	Scope       string
+	IP          string
+	Email       string
+	Failures    int
+	LockedUntil time.Time
+	CreatedAt   time.Time
+}
+
+// Active reports whether the lockout is still in force.
+func (e LockoutEvent) Active() bool {
+	return time.Now().Before(e.LockedUntil)
+}
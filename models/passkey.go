@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Passkey is a WebAuthn credential a user can sign in with instead of their
+// password. PublicKey is the COSE key the authenticator registered.
+type Passkey struct {
+	ID           int
+	AdminID      int
+	CredentialID []byte
+	PublicKey    []byte
+	SignCount    uint32
+	Name         string
+	CreatedAt    time.Time
+	LastUsedAt   *time.Time
+}
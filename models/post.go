@@ -8,16 +8,34 @@ import (
 	"github.com/yuin/goldmark"
 )
 
+// PostMeta holds free-form per-post fields stored in the posts.meta JSONB
+// column, e.g. "canonical_url" or "og_image".
+type PostMeta map[string]string
+
 type Post struct {
 	ID 				int
 	Title 		string
 	Tagline		string
 	Body			string
 	Slug			string
-	Published	bool
+	// AuthorID is the user who wrote the post, or 0 for posts that predate
+	// authors or whose author was removed.
+	AuthorID	int
+	Status		PostStatus
+	// PublishAt is when a scheduled post goes live.
+	PublishAt	*time.Time
+	// ReviewerID is the admin asked to review the post, or 0.
+	ReviewerID	int
 	BannerImageURL string
+	Metadata	PostMeta
+	Layout		string
+	// Version increments on every update; edits carry it so a stale form
+	// can't overwrite a newer save.
+	Version		int
 	CreatedAt	time.Time
 	UpdatedAt	time.Time
+	// DeletedAt is set while the post is in the trash.
+	DeletedAt	*time.Time
 }
 
 func (p Post) RenderedBody() template.HTML {
@@ -26,4 +44,32 @@ func (p Post) RenderedBody() template.HTML {
 		return template.HTML(p.Body)
 	}
 	return template.HTML(buf.String())
-}
\ No newline at end of file
+}
+
+// Meta returns the metadata value stored under key, or "" when it is unset.
+// Templates use it as {{.Post.Meta "canonical_url"}}.
+func (p Post) Meta(key string) string {
+	return p.Metadata[key]
+}
+
+// PublishedAt is the date visitors see on the post: when it was published,
+// or was scheduled to go live, falling back to its creation for posts that
+// predate the workflow.
+func (p Post) PublishedAt() time.Time {
+	if p.PublishAt != nil {
+		return *p.PublishAt
+	}
+	return p.CreatedAt
+}
+
+// Published reports whether visitors can see the post: it is published, or
+// scheduled and its publish time has passed.
+func (p Post) Published() bool {
+	switch p.Status {
+	case StatusPublished:
+		return true
+	case StatusScheduled:
+		return p.PublishAt != nil && !p.PublishAt.After(time.Now())
+	}
+	return false
+}
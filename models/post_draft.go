@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PostDraft is an editor autosave. It is kept apart from the post itself so
+// nothing reaches the published body until the author saves. PostID is 0 for
+// a post that hasn't been created yet.
+type PostDraft struct {
+	ID        int
+	AdminID   int
+	PostID    int
+	Title     string
+	Tagline   string
+	Body      string
+	UpdatedAt time.Time
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// PostImage is one image in a post's gallery. Images are shown in ascending
+// Position order.
+type PostImage struct {
+	ID        int
+	PostID    int
+	URL       string
+	Caption   string
+	AltText   string
+	Position  int
+	CreatedAt time.Time
+}
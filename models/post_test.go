@@ -3,6 +3,7 @@ package models
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestPost_RenderedBody(t *testing.T) {
@@ -44,3 +45,30 @@ func TestPost_RenderedBody(t *testing.T) {
 		})
 	}
 }
+
+
+func TestPost_Meta(t *testing.T) {
+	post := Post{Metadata: PostMeta{"canonical_url": "https://example.com/original"}}
+
+	if got := post.Meta("canonical_url"); got != "https://example.com/original" {
+		t.Errorf("expected canonical_url to be returned, got %q", got)
+	}
+	if got := post.Meta("missing"); got != "" {
+		t.Errorf("expected empty string for missing key, got %q", got)
+	}
+	if got := (Post{}).Meta("canonical_url"); got != "" {
+		t.Errorf("expected empty string for nil metadata, got %q", got)
+	}
+}
+
+func TestPost_PublishedAt(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	scheduled := created.AddDate(0, 0, 7)
+
+	if got := (Post{CreatedAt: created, PublishAt: &scheduled}).PublishedAt(); !got.Equal(scheduled) {
+		t.Errorf("expected the publish time, got %v", got)
+	}
+	if got := (Post{CreatedAt: created}).PublishedAt(); !got.Equal(created) {
+		t.Errorf("expected the creation time without a publish time, got %v", got)
+	}
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// ReviewComment is feedback left on a post during editorial review.
+type ReviewComment struct {
+	ID          int
+	PostID      int
+	AdminID     int
+	AuthorEmail string
+	Body        string
+	CreatedAt   time.Time
+}
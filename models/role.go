@@ -0,0 +1,64 @@
+package models
+
+// Role decides what a signed-in user may do. Authors write and submit their
+// own posts, editors run the review workflow for everyone's posts, and
+// admins can also manage users.
+type Role string
+
+const (
+	RoleAuthor Role = "author"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+// Roles lists every role from least to most privileged.
+var Roles = []Role{RoleAuthor, RoleEditor, RoleAdmin}
+
+// Permission names something a role may be allowed to do.
+type Permission string
+
+const (
+	// PermWritePosts allows creating posts and editing your own.
+	PermWritePosts Permission = "write_posts"
+	// PermEditAnyPost allows editing, bulk-changing and trashing anyone's
+	// posts.
+	PermEditAnyPost Permission = "edit_any_post"
+	// PermPublishPosts allows approving, scheduling and publishing, and
+	// pulling posts back out of those states.
+	PermPublishPosts Permission = "publish_posts"
+	// PermManageUsers allows adding, changing and removing users.
+	PermManageUsers Permission = "manage_users"
+)
+
+// permissions is the one place each role's abilities are defined.
+var permissions = map[Role][]Permission{
+	RoleAuthor: {PermWritePosts},
+	RoleEditor: {PermWritePosts, PermEditAnyPost, PermPublishPosts},
+	RoleAdmin:  {PermWritePosts, PermEditAnyPost, PermPublishPosts, PermManageUsers},
+}
+
+func (r Role) Valid() bool {
+	_, ok := permissions[r]
+	return ok
+}
+
+func (r Role) Label() string {
+	switch r {
+	case RoleAuthor:
+		return "Author"
+	case RoleEditor:
+		return "Editor"
+	case RoleAdmin:
+		return "Admin"
+	}
+	return string(r)
+}
+
+func (r Role) Can(p Permission) bool {
+	for _, granted := range permissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package models
+
+// PostStatus is a post's place in the editorial workflow:
+// draft → in review → approved → scheduled → published.
+type PostStatus string
+
+const (
+	StatusDraft     PostStatus = "draft"
+	StatusInReview  PostStatus = "in_review"
+	StatusApproved  PostStatus = "approved"
+	StatusScheduled PostStatus = "scheduled"
+	StatusPublished PostStatus = "published"
+)
+
+// Statuses lists every status in workflow order.
+var Statuses = []PostStatus{StatusDraft, StatusInReview, StatusApproved, StatusScheduled, StatusPublished}
+
+// transitions is the one place the workflow's allowed moves are defined.
+// Everything that changes a post's status checks it via CanTransition.
+var transitions = map[PostStatus][]PostStatus{
+	StatusDraft:     {StatusInReview},
+	StatusInReview:  {StatusDraft, StatusApproved},
+	StatusApproved:  {StatusDraft, StatusScheduled, StatusPublished},
+	StatusScheduled: {StatusDraft, StatusApproved, StatusPublished},
+	StatusPublished: {StatusDraft},
+}
+
+func (s PostStatus) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+func (s PostStatus) Label() string {
+	switch s {
+	case StatusDraft:
+		return "Draft"
+	case StatusInReview:
+		return "In review"
+	case StatusApproved:
+		return "Approved"
+	case StatusScheduled:
+		return "Scheduled"
+	case StatusPublished:
+		return "Published"
+	}
+	return string(s)
+}
+
+// Next returns the statuses a post in status s may move to.
+func (s PostStatus) Next() []PostStatus {
+	return transitions[s]
+}
+
+func CanTransition(from, to PostStatus) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusesAllowing returns every status from which a post may move to to.
+func StatusesAllowing(to PostStatus) []PostStatus {
+	var from []PostStatus
+	for _, s := range Statuses {
+		if CanTransition(s, to) {
+			from = append(from, s)
+		}
+	}
+	return from
+}
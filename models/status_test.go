@@ -0,0 +1,36 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to PostStatus
+		want     bool
+	}{
+		{StatusDraft, StatusInReview, true},
+		{StatusDraft, StatusPublished, false},
+		{StatusInReview, StatusApproved, true},
+		{StatusApproved, StatusScheduled, true},
+		{StatusScheduled, StatusPublished, true},
+		{StatusPublished, StatusDraft, true},
+		{StatusPublished, StatusApproved, false},
+		{StatusDraft, StatusDraft, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestStatusesAllowing(t *testing.T) {
+	got := StatusesAllowing(StatusPublished)
+	want := []PostStatus{StatusApproved, StatusScheduled}
+	if !slices.Equal(got, want) {
+		t.Errorf("StatusesAllowing(published) = %v, want %v", got, want)
+	}
+}
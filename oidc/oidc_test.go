@@ -0,0 +1,190 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/oidc/oidctest"
+)
+
+const redirectURI = "https://blog.example.com/login/oidc/callback"
+
+// authorize runs the browser's half of the flow for c and returns the
+// code the provider sends back.
+func authorize(t *testing.T, c *Client, state, nonce, verifier string) string {
+	t.Helper()
+	authURL, err := c.AuthCodeURL(context.Background(), redirectURI, state, nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	noFollow := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := noFollow.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	back, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || !strings.HasPrefix(back.String(), redirectURI) {
+		t.Fatalf("expected a redirect back to the client, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if back.Query().Get("state") != state {
+		t.Fatalf("expected the state to come back, got %q", back.Query().Get("state"))
+	}
+	return back.Query().Get("code")
+}
+
+func TestFlow(t *testing.T) {
+	p := oidctest.NewProvider(t, "blog", "s3cret/+")
+	p.SignInAs(map[string]any{"sub": "user-1", "email": "a@example.com", "email_verified": true, "name": "A"})
+	c := New(p.Issuer, "blog", "s3cret/+")
+
+	verifier, _ := NewRandom()
+	code := authorize(t, c, "state-1", "nonce-1", verifier)
+
+	claims, err := c.Exchange(context.Background(), redirectURI, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	want := Claims{Subject: "user-1", Email: "a@example.com", EmailVerified: true, Name: "A"}
+	if claims != want {
+		t.Errorf("got claims %+v, want %+v", claims, want)
+	}
+
+	if _, err := c.Exchange(context.Background(), redirectURI, code, verifier, "nonce-1"); err == nil {
+		t.Error("expected a used code to be rejected")
+	}
+}
+
+func TestFlow_IssuerWithTrailingSlash(t *testing.T) {
+	p := oidctest.NewProvider(t, "blog", "secret")
+	p.Issuer += "/"
+	p.SignInAs(map[string]any{"sub": "user-1"})
+
+	c := New(p.Issuer, "blog", "secret")
+	verifier, _ := NewRandom()
+	code := authorize(t, c, "state-1", "nonce-1", verifier)
+	if _, err := c.Exchange(context.Background(), redirectURI, code, verifier, "nonce-1"); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	// The issuer is compared exactly, so leaving off the slash is an error
+	// rather than something quietly fixed up.
+	trimmed := New(strings.TrimSuffix(p.Issuer, "/"), "blog", "secret")
+	if _, err := trimmed.AuthCodeURL(context.Background(), redirectURI, "s", "n", "v"); err == nil {
+		t.Error("expected discovery to reject an issuer without the provider's trailing slash")
+	}
+}
+
+func TestExchange_Rejects(t *testing.T) {
+	p := oidctest.NewProvider(t, "blog", "secret")
+	p.SignInAs(map[string]any{"sub": "user-1"})
+	ctx := context.Background()
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		c := New(p.Issuer, "blog", "secret")
+		verifier, _ := NewRandom()
+		code := authorize(t, c, "s", "n", verifier)
+		if _, err := c.Exchange(ctx, redirectURI, code, "not-the-verifier", "n"); err == nil {
+			t.Error("expected the exchange to fail without the PKCE verifier")
+		}
+	})
+	t.Run("wrong nonce", func(t *testing.T) {
+		c := New(p.Issuer, "blog", "secret")
+		verifier, _ := NewRandom()
+		code := authorize(t, c, "s", "n", verifier)
+		if _, err := c.Exchange(ctx, redirectURI, code, verifier, "other"); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken for a replayed token, got %v", err)
+		}
+	})
+	t.Run("wrong secret", func(t *testing.T) {
+		c := New(p.Issuer, "blog", "guess")
+		verifier, _ := NewRandom()
+		code := authorize(t, c, "s", "n", verifier)
+		if _, err := c.Exchange(ctx, redirectURI, code, verifier, "n"); err == nil {
+			t.Error("expected the exchange to fail with the wrong client secret")
+		}
+	})
+}
+
+func TestVerify(t *testing.T) {
+	p := oidctest.NewProvider(t, "blog", "secret")
+	other := oidctest.NewProvider(t, "blog", "secret")
+	c := New(p.Issuer, "blog", "secret")
+	ctx := context.Background()
+	md, err := c.discover(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"valid", p.IDToken("n", map[string]any{"sub": "u"}), true},
+		{"email verified as a string", p.IDToken("n", map[string]any{"sub": "u", "email_verified": "true"}), true},
+		{"several audiences with azp", p.IDToken("n", map[string]any{"sub": "u", "aud": []string{"blog", "api"}, "azp": "blog"}), true},
+		{"several audiences without azp", p.IDToken("n", map[string]any{"sub": "u", "aud": []string{"blog", "api"}}), false},
+		{"other audience", p.IDToken("n", map[string]any{"sub": "u", "aud": "someone-else"}), false},
+		{"other issuer", p.IDToken("n", map[string]any{"sub": "u", "iss": other.Issuer}), false},
+		{"expired", p.IDToken("n", map[string]any{"sub": "u", "exp": now.Add(-2 * time.Minute).Unix()}), false},
+		{"no expiry", p.IDToken("n", map[string]any{"sub": "u", "exp": nil}), false},
+		{"issued in the future", p.IDToken("n", map[string]any{"sub": "u", "iat": now.Add(time.Hour).Unix()}), false},
+		{"wrong nonce", p.IDToken("other", map[string]any{"sub": "u"}), false},
+		{"no subject", p.IDToken("n", nil), false},
+		{"signed by another key", other.IDToken("n", map[string]any{"sub": "u", "iss": p.Issuer}), false},
+		{"unknown key", p.Sign(map[string]any{"alg": "RS256", "kid": "rotated"}, map[string]any{"sub": "u"}), false},
+		{"alg none", strings.Join(strings.Split(p.Sign(map[string]any{"alg": "none", "kid": p.KeyID}, map[string]any{"sub": "u"}), ".")[:2], ".") + ".", false},
+		{"HS256", p.Sign(map[string]any{"alg": "HS256", "kid": p.KeyID}, map[string]any{"sub": "u"}), false},
+		{"not a JWT", "abc.def", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.verify(ctx, md, tt.token, "n", now)
+			if tt.ok && err != nil {
+				t.Errorf("expected the token to verify, got %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+
+	t.Run("tampered payload", func(t *testing.T) {
+		parts := strings.Split(p.IDToken("n", map[string]any{"sub": "u"}), ".")
+		forged := strings.Split(p.IDToken("n", map[string]any{"sub": "admin"}), ".")
+		parts[1] = forged[1] + "x"
+		if _, err := c.verify(ctx, md, strings.Join(parts, "."), "n", now); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+}
+
+func TestDiscover_IssuerMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"issuer":"https://evil.example.com","authorization_endpoint":"a","token_endpoint":"t","jwks_uri":"j"}`))
+	}))
+	defer server.Close()
+
+	c := New(server.URL, "blog", "secret")
+	if _, err := c.AuthCodeURL(context.Background(), redirectURI, "s", "n", "v"); err == nil {
+		t.Error("expected discovery to reject a document for another issuer")
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	// RFC 7636, appendix B.
+	if got := Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("Challenge = %q", got)
+	}
+}
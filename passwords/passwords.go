@@ -0,0 +1,143 @@
+// Package passwords hashes and checks admin passwords.
+//
+// A stored hash starts with its scheme and carries its own parameters, so
+// hashes made under older settings keep verifying after the defaults change.
+// New hashes use argon2id; bcrypt hashes from before the switch are still
+// accepted, and NeedsRehash tells the caller to replace them the next time
+// the plaintext is at hand.
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrUnknownScheme = errors.New("passwords: unknown hash scheme")
+	ErrMalformedHash = errors.New("passwords: malformed hash")
+)
+
+const argon2Prefix = "$argon2id$"
+
+// Stored hashes with parameters outside these limits are rejected as
+// malformed rather than verified, since zero time or threads panics and a
+// huge cost would tie up the server.
+const (
+	maxArgon2Time   = 16
+	maxArgon2Memory = 1 << 20 // KiB, so 1 GiB
+)
+
+// Argon2Params are the argon2id settings for a hash.
+type Argon2Params struct {
+	Time       uint32
+	Memory     uint32 // KiB
+	Threads    uint8
+	SaltLength uint32
+	KeyLength  uint32
+}
+
+// Default is used for new hashes. It follows the second recommended option
+// of RFC 9106 with fewer lanes. Hashes made with anything else are rehashed
+// as their owners sign in, so raising it upgrades every account over time.
+var Default = Argon2Params{
+	Time:       3,
+	Memory:     64 * 1024,
+	Threads:    2,
+	SaltLength: 16,
+	KeyLength:  32,
+}
+
+// Hash returns an encoded argon2id hash of password using Default, in the
+// PHC string format:
+//
+//	$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func Hash(password string) (string, error) {
+	p := Default
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version,
+		p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether password matches hash, whichever supported scheme
+// made it. An error means the hash itself couldn't be used, not that the
+// password was wrong.
+func Verify(hash, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, argon2Prefix):
+		p, salt, key, err := decodeArgon2(hash)
+		if err != nil {
+			return false, err
+		}
+		other := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLength)
+		return subtle.ConstantTimeCompare(key, other) == 1, nil
+	case isBcrypt(hash):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	return false, ErrUnknownScheme
+}
+
+// NeedsRehash reports whether hash was made with anything other than the
+// current scheme and Default parameters.
+func NeedsRehash(hash string) bool {
+	if !strings.HasPrefix(hash, argon2Prefix) {
+		return true
+	}
+	p, _, _, err := decodeArgon2(hash)
+	return err != nil || p != Default
+}
+
+func isBcrypt(hash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeArgon2 splits an encoded argon2id hash into its parameters, salt
+// and key.
+func decodeArgon2(hash string) (p Argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, ErrMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return p, nil, nil, ErrMalformedHash
+	}
+	if p.Time < 1 || p.Time > maxArgon2Time || p.Threads < 1 ||
+		p.Memory < 8*uint32(p.Threads) || p.Memory > maxArgon2Memory {
+		return p, nil, nil, ErrMalformedHash
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, ErrMalformedHash
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return p, nil, nil, ErrMalformedHash
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
+}
@@ -0,0 +1,129 @@
+package passwords
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap swaps Default for fast parameters for the rest of the test.
+func cheap(t *testing.T) {
+	old := Default
+	Default = Argon2Params{Time: 1, Memory: 64, Threads: 1, SaltLength: 16, KeyLength: 32}
+	t.Cleanup(func() { Default = old })
+}
+
+func TestHashAndVerify(t *testing.T) {
+	cheap(t)
+	hash, err := Hash("a long enough password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("unexpected encoding %q", hash)
+	}
+
+	if ok, err := Verify(hash, "a long enough password"); !ok || err != nil {
+		t.Errorf("expected the password to verify, got %v (err %v)", ok, err)
+	}
+	if ok, err := Verify(hash, "a wrong password"); ok || err != nil {
+		t.Errorf("expected a wrong password to fail, got %v (err %v)", ok, err)
+	}
+
+	other, _ := Hash("a long enough password")
+	if other == hash {
+		t.Error("expected each hash to get its own salt")
+	}
+}
+
+func TestVerify_Bcrypt(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("an old password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := Verify(string(hash), "an old password"); !ok || err != nil {
+		t.Errorf("expected bcrypt hashes to keep verifying, got %v (err %v)", ok, err)
+	}
+	if ok, err := Verify(string(hash), "not it"); ok || err != nil {
+		t.Errorf("expected a wrong password to fail, got %v (err %v)", ok, err)
+	}
+}
+
+func TestVerify_BadHashes(t *testing.T) {
+	tests := []struct {
+		hash string
+		want error
+	}{
+		{"", ErrUnknownScheme},
+		{"plaintext", ErrUnknownScheme},
+		{"$argon2i$v=19$m=64,t=1,p=1$c2FsdA$a2V5", ErrUnknownScheme},
+		{"$argon2id$v=19$m=64,t=1,p=1$c2FsdA", ErrMalformedHash},
+		{"$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5", ErrMalformedHash},
+		{"$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5", ErrMalformedHash},
+		{"$argon2id$v=19$m=64,t=1,p=1$!!$a2V5", ErrMalformedHash},
+		{"$argon2id$v=19$m=64,t=1,p=1$c2FsdA$", ErrMalformedHash},
+		{"$argon2id$v=19$m=64,t=0,p=1$c2FsdA$a2V5", ErrMalformedHash},
+		{"$argon2id$v=19$m=64,t=1,p=0$c2FsdA$a2V5", ErrMalformedHash},
+		{"$argon2id$v=19$m=0,t=1,p=1$c2FsdA$a2V5", ErrMalformedHash},
+		{"$argon2id$v=19$m=4294967295,t=1,p=1$c2FsdA$a2V5", ErrMalformedHash},
+		{"$argon2id$v=19$m=64,t=4294967295,p=1$c2FsdA$a2V5", ErrMalformedHash},
+	}
+	for _, tt := range tests {
+		if ok, err := Verify(tt.hash, "password"); ok || !errors.Is(err, tt.want) {
+			t.Errorf("Verify(%q) = %v, %v; want %v", tt.hash, ok, err, tt.want)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	cheap(t)
+	current, _ := Hash("a long enough password")
+	if NeedsRehash(current) {
+		t.Error("expected a hash with the default parameters to be current")
+	}
+
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("an old password"), bcrypt.MinCost)
+	if !NeedsRehash(string(bcryptHash)) {
+		t.Error("expected bcrypt hashes to need rehashing")
+	}
+
+	Default.Time = 2
+	if !NeedsRehash(current) {
+		t.Error("expected a hash with outdated parameters to need rehashing")
+	}
+	if !NeedsRehash("$argon2id$garbage") {
+		t.Error("expected a malformed hash to need rehashing")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		password string
+		want     error
+	}{
+		{"short", ErrTooShort},
+		{"elevenchars", ErrTooShort},
+		{"twelve chars", nil},
+		{strings.Repeat("é", MinLength), nil},
+		{strings.Repeat("a", MaxLength+1), ErrTooLong},
+		{"password1234", ErrCommon},
+		{"Password1234", ErrCommon},
+		{"Correct Horse Battery Staple", ErrCommon},
+		{"violet kettle under a fern", nil},
+	}
+	for _, tt := range tests {
+		if err := Check(tt.password); !errors.Is(err, tt.want) {
+			t.Errorf("Check(%q) = %v, want %v", tt.password, err, tt.want)
+		}
+	}
+}
+
+func TestCommonListIsLongEnough(t *testing.T) {
+	for password := range common() {
+		if len([]rune(password)) < MinLength {
+			t.Errorf("%q is shorter than MinLength and can never match", password)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package passwords
+
+import (
+	_ "embed"
+	"errors"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	MinLength = 12
+	// MaxLength bounds the work one sign-in attempt can ask for.
+	MaxLength = 256
+)
+
+var (
+	ErrTooShort = errors.New("passwords: too short")
+	ErrTooLong  = errors.New("passwords: too long")
+	ErrCommon   = errors.New("passwords: too common")
+)
+
+// commonList holds passwords that show up again and again in breach dumps,
+// one per line. Case is ignored when checking against it.
+//
+//go:embed common.txt
+var commonList string
+
+var common = sync.OnceValue(func() map[string]bool {
+	set := map[string]bool{}
+	for _, line := range strings.Split(commonList, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			set[strings.ToLower(line)] = true
+		}
+	}
+	return set
+})
+
+// Check applies the password policy: between MinLength and MaxLength
+// characters, and not on the common password list.
+func Check(password string) error {
+	switch n := utf8.RuneCountInString(password); {
+	case n < MinLength:
+		return ErrTooShort
+	case n > MaxLength:
+		return ErrTooLong
+	}
+	if common()[strings.ToLower(password)] {
+		return ErrCommon
+	}
+	return nil
+}
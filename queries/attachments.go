@@ -0,0 +1,101 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const attachmentColumns = `id, post_id, url, filename, content_type, size_bytes, position, created_at`
+
+func scanAttachment(row pgx.Row) (models.Attachment, error) {
+	var a models.Attachment
+	err := row.Scan(
+		&a.ID, &a.PostID, &a.URL, &a.Filename, &a.ContentType,
+		&a.SizeBytes, &a.Position, &a.CreatedAt,
+	)
+	return a, err
+}
+
+func GetPostAttachments(postID int) ([]models.Attachment, error) {
+	rows, err := database.Pool.Query(
+		context.Background(),
+		`SELECT `+attachmentColumns+`
+						FROM post_attachments WHERE post_id = $1
+						ORDER BY position, id`,
+		postID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing attachment: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+
+	return attachments, nil
+}
+
+// GetAttachmentsForPosts loads the attachments of several posts in one query,
+// keyed by post ID. It is used by the feed to avoid a query per item.
+func GetAttachmentsForPosts(postIDs []int) (map[int][]models.Attachment, error) {
+	rows, err := database.Pool.Query(
+		context.Background(),
+		`SELECT `+attachmentColumns+`
+						FROM post_attachments WHERE post_id = ANY($1)
+						ORDER BY post_id, position, id`,
+		postIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := make(map[int][]models.Attachment)
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing attachment: %w", err)
+		}
+		attachments[a.PostID] = append(attachments[a.PostID], a)
+	}
+
+	return attachments, nil
+}
+
+// CreateAttachment appends an attachment to the end of a post's download list.
+func CreateAttachment(postID int, url, filename, contentType string, sizeBytes int64) (models.Attachment, error) {
+	a, err := scanAttachment(database.Pool.QueryRow(
+		context.Background(),
+		`INSERT INTO post_attachments (post_id, url, filename, content_type, size_bytes, position)
+						VALUES ($1, $2, $3, $4, $5,
+							(SELECT COALESCE(MAX(position), 0) + 1 FROM post_attachments WHERE post_id = $1))
+						RETURNING `+attachmentColumns,
+		postID, url, filename, contentType, sizeBytes,
+	))
+	if err != nil {
+		return models.Attachment{}, fmt.Errorf("error creating attachment: %w", err)
+	}
+	return a, nil
+}
+
+func DeleteAttachment(id int) error {
+	_, err := database.Pool.Exec(
+		context.Background(),
+		`DELETE FROM post_attachments WHERE id=$1`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("error deleting attachment: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+// ListAdmins returns every user, ordered by email, for reviewer pickers and
+// user management.
+func ListAdmins() ([]models.Admin, error) {
+	rows, err := database.Pool.Query(
+		context.Background(),
+		`SELECT `+adminColumns+` FROM admins ORDER BY email`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying admins: %w", err)
+	}
+	defer rows.Close()
+
+	var admins []models.Admin
+	for rows.Next() {
+		a, err := scanAdmin(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing admin: %w", err)
+		}
+		admins = append(admins, a)
+	}
+
+	return admins, nil
+}
+
+// GetAuthorBySlug returns the user whose public author page is at
+// /authors/{slug}.
+func GetAuthorBySlug(slug string) (models.Admin, error) {
+	a, err := scanAdmin(database.Pool.QueryRow(
+		context.Background(),
+		`SELECT `+adminColumns+` FROM admins WHERE slug = $1`,
+		slug,
+	))
+	if err != nil {
+		return models.Admin{}, fmt.Errorf("author not found: %w", err)
+	}
+	return a, nil
+}
+
+// AuthorSlugTaken reports whether a user other than exceptID already has
+// slug.
+func AuthorSlugTaken(slug string, exceptID int) (bool, error) {
+	var exists bool
+	err := database.Pool.QueryRow(
+		context.Background(),
+		`SELECT EXISTS(SELECT 1 FROM admins WHERE slug = $1 AND id <> $2)`,
+		slug, exceptID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking author slug: %w", err)
+	}
+	return exists, nil
+}
+
+// UpdateProfile saves the public author profile for a user.
+func UpdateProfile(id int, name, slug, bio, avatarURL string) (models.Admin, error) {
+	a, err := scanAdmin(database.Pool.QueryRow(
+		context.Background(),
+		`UPDATE admins SET name=$1, slug=NULLIF($2, ''), bio=$3, avatar_url=$4, updated_at=NOW()
+			WHERE id=$5
+			RETURNING `+adminColumns,
+		name, slug, bio, avatarURL, id,
+	))
+	if err != nil {
+		return models.Admin{}, fmt.Errorf("error updating profile: %w", err)
+	}
+	return a, nil
+}
+
+func SetAdminRole(id int, role models.Role) error {
+	return execAdmin("updating role",
+		`UPDATE admins SET role=$1, updated_at=NOW() WHERE id=$2`, string(role), id)
+}
+
+func CountPublishedPostsByAuthor(authorID int) (int, error) {
+	var count int
+	err := database.Pool.QueryRow(
+		context.Background(),
+		`SELECT COUNT(*) FROM posts WHERE author_id = $1 AND `+publicCondition,
+		authorID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting posts: %w", err)
+	}
+	return count, nil
+}
+
+// GetPublishedPostsByAuthor returns one page of an author's public posts,
+// newest first.
+func GetPublishedPostsByAuthor(authorID, page, perPage int) ([]models.Post, error) {
+	rows, err := database.Pool.Query(
+		context.Background(),
+		`SELECT `+postColumns+` FROM posts
+			WHERE author_id = $1 AND `+publicCondition+`
+			ORDER BY `+publicOrder+`
+			LIMIT $2 OFFSET $3`,
+		authorID, perPage, (page-1)*perPage,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying posts: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []models.Post
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing post: %w", err)
+		}
+		posts = append(posts, p)
+	}
+
+	return posts, nil
+}
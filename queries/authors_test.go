@@ -0,0 +1,50 @@
+package queries
+
+import (
+	"testing"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+func TestAuthorProfileAndPosts(t *testing.T) {
+	author, err := CreateAdmin("author-test@example.com", "hash", models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("Error creating admin: %v", err)
+	}
+	post, err := CreatePost("Authored", "tag", "body", "authored-post", "", nil, "", author.ID)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+
+	t.Cleanup(func() {
+		DeletePost(post.ID)
+		DeleteAdmin(author.ID)
+	})
+
+	if post.AuthorID != author.ID {
+		t.Errorf("expected post to be written by %d, got %d", author.ID, post.AuthorID)
+	}
+
+	if _, err := UpdateProfile(author.ID, "Author Test", "author-test", "Hello.", ""); err != nil {
+		t.Fatalf("Error updating profile: %v", err)
+	}
+	found, err := GetAuthorBySlug("author-test")
+	if err != nil || found.ID != author.ID || found.Name != "Author Test" {
+		t.Fatalf("expected to find the author by slug, got %+v, %v", found, err)
+	}
+	if taken, _ := AuthorSlugTaken("author-test", author.ID); taken {
+		t.Error("expected an author's own slug not to count as taken")
+	}
+
+	if count, _ := CountPublishedPostsByAuthor(author.ID); count != 0 {
+		t.Errorf("expected drafts to be left off the author page, got %d", count)
+	}
+	publish(t, post.ID)
+	posts, err := GetPublishedPostsByAuthor(author.ID, 1, 10)
+	if err != nil {
+		t.Fatalf("Error listing posts: %v", err)
+	}
+	if len(posts) != 1 || posts[0].ID != post.ID {
+		t.Errorf("expected the published post on the author page, got %d posts", len(posts))
+	}
+}
@@ -0,0 +1,42 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+	"github.com/jackc/pgx/v5"
+)
+
+// withTx runs fn in a transaction, committing if it returns nil and rolling
+// back otherwise.
+func withTx(fn func(tx pgx.Tx) error) error {
+	ctx := context.Background()
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// BulkTrashPosts moves the given posts to the trash and reports how many
+// were moved.
+func BulkTrashPosts(ids []int) (int64, error) {
+	tag, err := database.Pool.Exec(
+		context.Background(),
+		`UPDATE posts SET deleted_at=NOW() WHERE id = ANY($1) AND deleted_at IS NULL`,
+		ids,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error trashing posts: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
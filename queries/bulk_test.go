@@ -0,0 +1,87 @@
+package queries
+
+import (
+	"testing"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+func TestBulkTransition_CountsOnlyChangedPosts(t *testing.T) {
+	draft, err := CreatePost("Bulk Draft", "tag", "body", "bulk-draft", "", nil, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+	published, err := CreatePost("Bulk Published", "tag", "body", "bulk-published", "", nil, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+
+	t.Cleanup(func() {
+		DeletePost(draft.ID)
+		DeletePost(published.ID)
+	})
+	publish(t, published.ID)
+
+	// Drafts can't skip review, and published posts are already there.
+	changed, err := BulkTransition([]int{draft.ID, published.ID}, models.StatusPublished)
+	if err != nil {
+		t.Fatalf("Error publishing posts: %v", err)
+	}
+	if changed != 0 {
+		t.Errorf("expected no posts to change, got %d", changed)
+	}
+
+	changed, err = BulkTransition([]int{draft.ID, published.ID}, models.StatusDraft)
+	if err != nil {
+		t.Fatalf("Error moving posts to draft: %v", err)
+	}
+	if changed != 1 {
+		t.Errorf("expected 1 post to change, got %d", changed)
+	}
+
+	changed, err = BulkTrashPosts([]int{draft.ID, published.ID})
+	if err != nil {
+		t.Fatalf("Error trashing posts: %v", err)
+	}
+	if changed != 2 {
+		t.Errorf("expected 2 posts trashed, got %d", changed)
+	}
+}
+
+func TestBulkTagPosts(t *testing.T) {
+	first, err := CreatePost("Bulk Tag One", "tag", "body", "bulk-tag-one", "", nil, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+	second, err := CreatePost("Bulk Tag Two", "tag", "body", "bulk-tag-two", "", nil, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+	t.Cleanup(func() {
+		DeletePost(first.ID)
+		DeletePost(second.ID)
+	})
+	ids := []int{first.ID, second.ID}
+
+	if changed, err := BulkTagPosts([]int{first.ID}, "golang"); err != nil || changed != 1 {
+		t.Fatalf("expected 1 post tagged, got %d, %v", changed, err)
+	}
+	// Posts that already have the tag aren't counted again.
+	if changed, err := BulkTagPosts(ids, "golang"); err != nil || changed != 1 {
+		t.Errorf("expected only the untagged post to change, got %d, %v", changed, err)
+	}
+	tags, err := GetTagsForPosts(ids)
+	if err != nil {
+		t.Fatalf("Error fetching tags: %v", err)
+	}
+	if len(tags[first.ID]) != 1 || len(tags[second.ID]) != 1 {
+		t.Errorf("expected both posts tagged once, got %v", tags)
+	}
+
+	if changed, err := BulkUntagPosts(ids, "golang"); err != nil || changed != 2 {
+		t.Errorf("expected 2 posts untagged, got %d, %v", changed, err)
+	}
+	if tags, _ := GetTagsForPosts(ids); len(tags) != 0 {
+		t.Errorf("expected no tags left, got %v", tags)
+	}
+}
@@ -0,0 +1,120 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+// PostFilter narrows and orders the admin post listing. Zero values mean
+// "no constraint", so PostFilter{} lists every post that isn't trashed,
+// newest first.
+type PostFilter struct {
+	Status models.PostStatus // "" for any status
+	// WaitingOn limits the listing to posts in review assigned to this admin.
+	WaitingOn int
+	// AuthorID limits the listing to one author's posts.
+	AuthorID int
+	Search   string    // matched case-insensitively against title, tagline and body
+	From     time.Time // created on or after
+	To       time.Time // created before
+	Sort     string    // one of the keys in postSortColumns
+	Desc     bool
+}
+
+// postSortColumns whitelists the columns the dashboard may sort by, keyed by
+// the value used in the URL.
+var postSortColumns = map[string]string{
+	"title":   "title",
+	"status":  "status",
+	"created": "created_at",
+	"updated": "updated_at",
+}
+
+func (f PostFilter) where() (string, []any) {
+	conds := []string{"deleted_at IS NULL"}
+	var args []any
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.Status.Valid() {
+		conds = append(conds, "status = "+arg(string(f.Status)))
+	}
+	if f.WaitingOn != 0 {
+		conds = append(conds, fmt.Sprintf("status = '%s' AND reviewer_id = %s", models.StatusInReview, arg(f.WaitingOn)))
+	}
+	if f.AuthorID != 0 {
+		conds = append(conds, "author_id = "+arg(f.AuthorID))
+	}
+	if f.Search != "" {
+		p := arg("%" + f.Search + "%")
+		conds = append(conds, fmt.Sprintf("(title ILIKE %s OR tagline ILIKE %s OR body ILIKE %s)", p, p, p))
+	}
+	if !f.From.IsZero() {
+		conds = append(conds, "created_at >= "+arg(f.From))
+	}
+	if !f.To.IsZero() {
+		conds = append(conds, "created_at < "+arg(f.To))
+	}
+
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+func (f PostFilter) orderBy() string {
+	col, ok := postSortColumns[f.Sort]
+	if !ok {
+		col = "created_at"
+	}
+	dir := "ASC"
+	if f.Desc || !ok {
+		dir = "DESC"
+	}
+	return fmt.Sprintf("ORDER BY %s %s, id DESC", col, dir)
+}
+
+func CountPosts(f PostFilter) (int, error) {
+	where, args := f.where()
+	var count int
+	err := database.Pool.QueryRow(
+		context.Background(),
+		`SELECT COUNT(*) FROM posts `+where,
+		args...,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting posts: %w", err)
+	}
+	return count, nil
+}
+
+// ListPosts returns one page of posts of any status matching f, for the
+// admin dashboard.
+func ListPosts(f PostFilter, page, perPage int) ([]models.Post, error) {
+	where, args := f.where()
+	args = append(args, perPage, (page-1)*perPage)
+	query := fmt.Sprintf(`SELECT %s FROM posts %s %s LIMIT $%d OFFSET $%d`,
+		postColumns, where, f.orderBy(), len(args)-1, len(args))
+
+	rows, err := database.Pool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying posts: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []models.Post
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing post: %w", err)
+		}
+		posts = append(posts, p)
+	}
+
+	return posts, nil
+}
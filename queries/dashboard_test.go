@@ -0,0 +1,57 @@
+package queries
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPostFilter_OrderByRejectsUnknownColumns(t *testing.T) {
+	got := PostFilter{Sort: "title; DROP TABLE posts"}.orderBy()
+	if got != "ORDER BY created_at DESC, id DESC" {
+		t.Errorf("expected default ordering for unknown sort, got %q", got)
+	}
+
+	got = PostFilter{Sort: "title"}.orderBy()
+	if got != "ORDER BY title ASC, id DESC" {
+		t.Errorf("expected title ascending, got %q", got)
+	}
+}
+
+func TestListPosts_FiltersByStatusAndSearch(t *testing.T) {
+	draft, err := CreatePost("Dashboard Draft Zebra", "tag", "body", "dashboard-draft-zebra", "", nil, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+	published, err := CreatePost("Dashboard Published Zebra", "tag", "body", "dashboard-published-zebra", "", nil, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+
+	t.Cleanup(func() {
+		DeletePost(draft.ID)
+		DeletePost(published.ID)
+	})
+	publish(t, published.ID)
+
+	filter := PostFilter{Status: "draft", Search: "zebra"}
+	posts, err := ListPosts(filter, 1, 10)
+	if err != nil {
+		t.Fatalf("Error listing posts: %v", err)
+	}
+	if len(posts) != 1 || posts[0].ID != draft.ID {
+		t.Errorf("expected only the draft, got %d posts", len(posts))
+	}
+
+	count, err := CountPosts(PostFilter{Search: "ZEBRA"})
+	if err != nil {
+		t.Fatalf("Error counting posts: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected case-insensitive search to match 2 posts, got %d", count)
+	}
+
+	where, _ := filter.where()
+	if !strings.Contains(where, "deleted_at IS NULL") {
+		t.Errorf("expected trashed posts to be excluded, got %q", where)
+	}
+}
@@ -0,0 +1,56 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+// SaveDraft upserts the autosave for an admin's editor. Pass postID 0 for
+// the "new post" editor.
+func SaveDraft(adminID, postID int, title, tagline, body string) (models.PostDraft, error) {
+	var d models.PostDraft
+	err := database.Pool.QueryRow(
+		context.Background(),
+		`INSERT INTO post_drafts (admin_id, post_id, title, tagline, body, updated_at)
+						VALUES ($1, NULLIF($2, 0), $3, $4, $5, NOW())
+						ON CONFLICT (admin_id, (COALESCE(post_id, 0)))
+						DO UPDATE SET title=EXCLUDED.title, tagline=EXCLUDED.tagline,
+							body=EXCLUDED.body, updated_at=NOW()
+						RETURNING id, admin_id, COALESCE(post_id, 0), title, tagline, body, updated_at`,
+		adminID, postID, title, tagline, body,
+	).Scan(&d.ID, &d.AdminID, &d.PostID, &d.Title, &d.Tagline, &d.Body, &d.UpdatedAt)
+	if err != nil {
+		return models.PostDraft{}, fmt.Errorf("error saving draft: %w", err)
+	}
+	return d, nil
+}
+
+func GetDraft(adminID, postID int) (models.PostDraft, error) {
+	var d models.PostDraft
+	err := database.Pool.QueryRow(
+		context.Background(),
+		`SELECT id, admin_id, COALESCE(post_id, 0), title, tagline, body, updated_at
+						FROM post_drafts
+						WHERE admin_id = $1 AND COALESCE(post_id, 0) = $2`,
+		adminID, postID,
+	).Scan(&d.ID, &d.AdminID, &d.PostID, &d.Title, &d.Tagline, &d.Body, &d.UpdatedAt)
+	if err != nil {
+		return models.PostDraft{}, fmt.Errorf("draft not found: %w", err)
+	}
+	return d, nil
+}
+
+func DeleteDraft(adminID, postID int) error {
+	_, err := database.Pool.Exec(
+		context.Background(),
+		`DELETE FROM post_drafts WHERE admin_id = $1 AND COALESCE(post_id, 0) = $2`,
+		adminID, postID,
+	)
+	if err != nil {
+		return fmt.Errorf("error deleting draft: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package queries
+
+import (
+	"testing"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+func TestDraftUpsertAndDelete(t *testing.T) {
+	admin, err := CreateAdmin("drafts-test@example.com", "not-a-real-hash", models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("Error creating admin: %v", err)
+	}
+
+	t.Cleanup(func() {
+		DeleteAdmin(admin.ID)
+	})
+
+	if _, err := SaveDraft(admin.ID, 0, "First", "", "one"); err != nil {
+		t.Fatalf("Error saving draft: %v", err)
+	}
+	if _, err := SaveDraft(admin.ID, 0, "Second", "", "two"); err != nil {
+		t.Fatalf("Error saving draft again: %v", err)
+	}
+
+	draft, err := GetDraft(admin.ID, 0)
+	if err != nil {
+		t.Fatalf("Error fetching draft: %v", err)
+	}
+	if draft.Title != "Second" || draft.Body != "two" {
+		t.Errorf("expected the latest autosave to win, got %+v", draft)
+	}
+
+	if err := DeleteDraft(admin.ID, 0); err != nil {
+		t.Fatalf("Error deleting draft: %v", err)
+	}
+	if _, err := GetDraft(admin.ID, 0); err == nil {
+		t.Error("expected draft to be gone after delete")
+	}
+}
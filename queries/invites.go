@@ -0,0 +1,159 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrInviteInvalid is returned for an invite token that doesn't exist, has
+// expired, was revoked or has already been used.
+var ErrInviteInvalid = errors.New("invite link is invalid or has expired")
+
+const inviteColumns = `i.id, i.email, i.role, i.invited_by, COALESCE(a.email, ''), i.expires_at,
+			i.accepted_at, i.accepted_admin_id, i.created_at`
+
+const inviteFrom = `FROM admin_invites i LEFT JOIN admins a ON a.id = i.invited_by`
+
+func scanInvite(row pgx.Row) (models.Invite, error) {
+	var i models.Invite
+	var role string
+	err := row.Scan(&i.ID, &i.Email, &role, &i.InvitedBy, &i.InvitedByEmail, &i.ExpiresAt,
+		&i.AcceptedAt, &i.AcceptedAdminID, &i.CreatedAt)
+	i.Role = models.Role(role)
+	return i, err
+}
+
+// CreateInvite stores an invite from invitedBy, valid for ttl. Only the
+// token's hash is kept, so the link can't be recovered later.
+func CreateInvite(email string, role models.Role, invitedBy int, tokenHash string, ttl time.Duration) (models.Invite, error) {
+	var id int
+	err := database.Pool.QueryRow(
+		context.Background(),
+		`INSERT INTO admin_invites (email, role, invited_by, token_hash, expires_at)
+						VALUES ($1, $2, $3, $4, NOW() + $5::interval)
+						RETURNING id`,
+		email, string(role), invitedBy, tokenHash, ttl,
+	).Scan(&id)
+	if err != nil {
+		return models.Invite{}, fmt.Errorf("error creating invite: %w", err)
+	}
+	return getInvite(`i.id = $1`, id)
+}
+
+// GetInviteByToken returns the pending invite a token is for.
+func GetInviteByToken(tokenHash string) (models.Invite, error) {
+	return getInvite(`i.token_hash = $1 AND i.accepted_at IS NULL AND i.expires_at > NOW()`, tokenHash)
+}
+
+func getInvite(where string, arg any) (models.Invite, error) {
+	i, err := scanInvite(database.Pool.QueryRow(
+		context.Background(),
+		`SELECT `+inviteColumns+` `+inviteFrom+` WHERE `+where,
+		arg,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Invite{}, ErrInviteInvalid
+	}
+	if err != nil {
+		return models.Invite{}, fmt.Errorf("error loading invite: %w", err)
+	}
+	return i, nil
+}
+
+// ListPendingInvites returns the invites that can still be used, newest
+// first.
+func ListPendingInvites() ([]models.Invite, error) {
+	rows, err := database.Pool.Query(
+		context.Background(),
+		`SELECT `+inviteColumns+` `+inviteFrom+`
+						WHERE i.accepted_at IS NULL AND i.expires_at > NOW()
+						ORDER BY i.created_at DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error listing invites: %w", err)
+	}
+	defer rows.Close()
+
+	var invites []models.Invite
+	for rows.Next() {
+		i, err := scanInvite(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning invite: %w", err)
+		}
+		invites = append(invites, i)
+	}
+	return invites, rows.Err()
+}
+
+// RevokeInvite deletes a pending invite so its link stops working.
+func RevokeInvite(id int) error {
+	tag, err := database.Pool.Exec(
+		context.Background(),
+		`DELETE FROM admin_invites WHERE id=$1 AND accepted_at IS NULL`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("error revoking invite: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInviteInvalid
+	}
+	return nil
+}
+
+// AcceptInvite uses up a pending invite and creates the account it was for,
+// with the invite's role. The invite's email, if it has one, wins over the
+// email passed in. Both happen in one transaction, so an invite can't be
+// used twice, and a taken email (ErrEmailTaken) leaves the invite usable.
+func AcceptInvite(tokenHash, email, hashedPassword string) (models.Admin, error) {
+	var admin models.Admin
+	err := withTx(func(tx pgx.Tx) error {
+		ctx := context.Background()
+		var inviteID int
+		var inviteEmail, role string
+		err := tx.QueryRow(ctx,
+			`SELECT id, email, role FROM admin_invites
+							WHERE token_hash=$1 AND accepted_at IS NULL AND expires_at > NOW()
+							FOR UPDATE`,
+			tokenHash,
+		).Scan(&inviteID, &inviteEmail, &role)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInviteInvalid
+		}
+		if err != nil {
+			return fmt.Errorf("error loading invite: %w", err)
+		}
+		if inviteEmail != "" {
+			email = inviteEmail
+		}
+
+		admin, err = scanAdmin(tx.QueryRow(ctx,
+			`INSERT INTO admins (email, encrypted_password, role)
+							VALUES ($1, $2, $3)
+							RETURNING `+adminColumns,
+			email, hashedPassword, role,
+		))
+		if err != nil {
+			return adminError("creating admin", err)
+		}
+
+		_, err = tx.Exec(ctx,
+			`UPDATE admin_invites SET accepted_at=NOW(), accepted_admin_id=$1 WHERE id=$2`,
+			admin.ID, inviteID,
+		)
+		if err != nil {
+			return fmt.Errorf("error accepting invite: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Admin{}, err
+	}
+	return admin, nil
+}
@@ -0,0 +1,82 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+func TestInvites(t *testing.T) {
+	inviter, err := CreateAdmin("inviter-test@example.com", "hash", models.RoleAdmin)
+	if err != nil {
+		t.Fatalf("Error creating admin: %v", err)
+	}
+	var created []int
+	t.Cleanup(func() {
+		database.Pool.Exec(context.Background(), `DELETE FROM admin_invites WHERE invited_by=$1`, inviter.ID)
+		for _, id := range created {
+			DeleteAdmin(id)
+		}
+		DeleteAdmin(inviter.ID)
+	})
+
+	open, err := CreateInvite("", models.RoleAuthor, inviter.ID, "open-invite", time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating invite: %v", err)
+	}
+	if open.InvitedByEmail != inviter.Email || open.Role != models.RoleAuthor || !open.Pending() {
+		t.Errorf("unexpected invite %+v", open)
+	}
+	if _, err := CreateInvite("named-invitee@example.com", models.RoleEditor, inviter.ID, "named-invite", time.Hour); err != nil {
+		t.Fatalf("Error creating invite: %v", err)
+	}
+
+	pending, err := ListPendingInvites()
+	if err != nil || len(pending) < 2 {
+		t.Fatalf("expected both invites to be pending, got %d (err %v)", len(pending), err)
+	}
+
+	if _, err := AcceptInvite("open-invite", inviter.Email, "new-hash"); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("expected ErrEmailTaken for an existing email, got %v", err)
+	}
+	admin, err := AcceptInvite("open-invite", "open-invitee@example.com", "new-hash")
+	if err != nil {
+		t.Fatalf("Error accepting invite: %v", err)
+	}
+	created = append(created, admin.ID)
+	if admin.Email != "open-invitee@example.com" || admin.Role != models.RoleAuthor {
+		t.Errorf("unexpected account %+v", admin)
+	}
+	if _, err := AcceptInvite("open-invite", "again@example.com", "new-hash"); !errors.Is(err, ErrInviteInvalid) {
+		t.Errorf("expected a used invite to be rejected, got %v", err)
+	}
+	if _, err := GetInviteByToken("open-invite"); !errors.Is(err, ErrInviteInvalid) {
+		t.Errorf("expected a used invite not to be found, got %v", err)
+	}
+
+	named, err := AcceptInvite("named-invite", "someone-else@example.com", "new-hash")
+	if err != nil {
+		t.Fatalf("Error accepting invite: %v", err)
+	}
+	created = append(created, named.ID)
+	if named.Email != "named-invitee@example.com" || named.Role != models.RoleEditor {
+		t.Errorf("expected the invite's email and role to be used, got %+v", named)
+	}
+
+	revoked, _ := CreateInvite("", models.RoleAuthor, inviter.ID, "revoked-invite", time.Hour)
+	if err := RevokeInvite(revoked.ID); err != nil {
+		t.Fatalf("Error revoking invite: %v", err)
+	}
+	if _, err := GetInviteByToken("revoked-invite"); !errors.Is(err, ErrInviteInvalid) {
+		t.Errorf("expected a revoked invite to be rejected, got %v", err)
+	}
+
+	CreateInvite("", models.RoleAuthor, inviter.ID, "expired-invite", -time.Minute)
+	if _, err := AcceptInvite("expired-invite", "late@example.com", "new-hash"); !errors.Is(err, ErrInviteInvalid) {
+		t.Errorf("expected an expired invite to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+// FailedLogins summarises recent failed logins for one email or address.
+// Last is zero when there are none.
+type FailedLogins struct {
+	Count int
+	Last  time.Time
+}
+
+// StartLoginAttempt logs a login attempt for throttling, as failed until
+// MarkLoginSucceeded says otherwise, and returns its ID. It is recorded
+// before the credentials are checked so that attempts running at the same
+// time count each other, rather than all seeing the same earlier count.
+// email should be normalised by the caller so that case changes don't dodge
+// the count.
+func StartLoginAttempt(ip, email string) (int64, error) {
+	var id int64
+	err := database.Pool.QueryRow(
+		context.Background(),
+		`INSERT INTO login_attempts (ip, email, succeeded) VALUES ($1, $2, FALSE) RETURNING id`,
+		ip, email,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error recording login attempt: %w", err)
+	}
+	return id, nil
+}
+
+// MarkLoginSucceeded records that the attempt with the given ID succeeded.
+func MarkLoginSucceeded(id int64) error {
+	_, err := database.Pool.Exec(
+		context.Background(),
+		`UPDATE login_attempts SET succeeded = TRUE WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("error recording login attempt: %w", err)
+	}
+	return nil
+}
+
+// DeleteLoginAttempt removes an attempt that was turned away before its
+// credentials were checked, so it doesn't count as a failure.
+func DeleteLoginAttempt(id int64) error {
+	_, err := database.Pool.Exec(
+		context.Background(),
+		`DELETE FROM login_attempts WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("error removing login attempt: %w", err)
+	}
+	return nil
+}
+
+// FailedLoginsForEmail counts failed logins for email within window, since
+// its last successful login, leaving out the attempt with ID except.
+func FailedLoginsForEmail(email string, window time.Duration, except int64) (FailedLogins, error) {
+	return failedLogins(
+		`email = $1 AND created_at > COALESCE(
+			(SELECT MAX(created_at) FROM login_attempts WHERE email = $1 AND succeeded),
+			'-infinity')`,
+		email, window, except,
+	)
+}
+
+// FailedLoginsForIP counts failed logins from ip within window. Unlike
+// FailedLoginsForEmail, a success doesn't reset the count, or signing in to
+// one account would clear the way for guessing at others.
+func FailedLoginsForIP(ip string, window time.Duration, except int64) (FailedLogins, error) {
+	return failedLogins(`ip = $1`, ip, window, except)
+}
+
+func failedLogins(condition, value string, window time.Duration, except int64) (FailedLogins, error) {
+	var f FailedLogins
+	var last *time.Time
+	err := database.Pool.QueryRow(
+		context.Background(),
+		`SELECT COUNT(*), MAX(created_at) FROM login_attempts
+						WHERE NOT succeeded AND created_at > NOW() - $2::interval AND id <> $3 AND `+condition,
+		value, window, except,
+	).Scan(&f.Count, &last)
+	if err != nil {
+		return FailedLogins{}, fmt.Errorf("error counting failed logins: %w", err)
+	}
+	if last != nil {
+		f.Last = *last
+	}
+	return f, nil
+}
+
+// PruneLoginAttempts deletes attempts older than retention and reports how
+// many were removed.
+func PruneLoginAttempts(retention time.Duration) (int64, error) {
+	tag, err := database.Pool.Exec(
+		context.Background(),
+		`DELETE FROM login_attempts WHERE created_at < NOW() - $1::interval`,
+		retention,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error pruning login attempts: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// RecordLockout logs a lockout so admins can see it on the security page.
+func RecordLockout(scope, ip, email string, failures int, until time.Time) error {
+	_, err := database.Pool.Exec(
+		context.Background(),
+		`INSERT INTO lockout_events (scope, ip, email, failures, locked_until)
+						VALUES ($1, $2, $3, $4, $5)`,
+		scope, ip, email, failures, until,
+	)
+	if err != nil {
+		return fmt.Errorf("error recording lockout: %w", err)
+	}
+	return nil
+}
+
+// GetLockoutEvents returns the most recent lockouts, newest first.
+func GetLockoutEvents(limit int) ([]models.LockoutEvent, error) {
+	rows, err := database.Pool.Query(
+		context.Background(),
+		`SELECT id, scope, ip, email, failures, locked_until, created_at
+						FROM lockout_events ORDER BY created_at DESC LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying lockout events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.LockoutEvent
+	for rows.Next() {
+		var e models.LockoutEvent
+		if err := rows.Scan(&e.ID, &e.Scope, &e.IP, &e.Email, &e.Failures, &e.LockedUntil, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error parsing lockout event: %w", err)
+		}
+		events = append(events, e)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,51 @@
+package queries
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+)
+
+func TestFailedLogins(t *testing.T) {
+	const ip, email = "203.0.113.7", "failed-logins@example.com"
+	t.Cleanup(func() {
+		database.Pool.Exec(context.Background(), `DELETE FROM login_attempts WHERE ip = $1`, ip)
+	})
+
+	for range 3 {
+		if _, err := StartLoginAttempt(ip, email); err != nil {
+			t.Fatalf("Error recording attempt: %v", err)
+		}
+	}
+
+	byEmail, err := FailedLoginsForEmail(email, time.Hour, 0)
+	if err != nil {
+		t.Fatalf("Error counting failures: %v", err)
+	}
+	if byEmail.Count != 3 || byEmail.Last.IsZero() {
+		t.Errorf("expected 3 failures with a last time, got %+v", byEmail)
+	}
+
+	id, err := StartLoginAttempt(ip, email)
+	if err != nil {
+		t.Fatalf("Error recording attempt: %v", err)
+	}
+	if byEmail, _ := FailedLoginsForEmail(email, time.Hour, id); byEmail.Count != 3 {
+		t.Errorf("expected the attempt being made to be left out, got %d", byEmail.Count)
+	}
+	if byEmail, _ := FailedLoginsForEmail(email, time.Hour, 0); byEmail.Count != 4 {
+		t.Errorf("expected an unfinished attempt to count as failed, got %d", byEmail.Count)
+	}
+	if err := MarkLoginSucceeded(id); err != nil {
+		t.Fatalf("Error recording success: %v", err)
+	}
+
+	if byEmail, _ := FailedLoginsForEmail(email, time.Hour, 0); byEmail.Count != 0 {
+		t.Errorf("expected a successful login to reset the account's count, got %d", byEmail.Count)
+	}
+	if byIP, _ := FailedLoginsForIP(ip, time.Hour, 0); byIP.Count != 3 {
+		t.Errorf("expected a successful login to leave the address's count alone, got %d", byIP.Count)
+	}
+}
@@ -0,0 +1,109 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrPasskeyNotFound is returned when no passkey matches, or it belongs to
+// another user.
+var ErrPasskeyNotFound = errors.New("passkey not found")
+
+const passkeyColumns = `id, admin_id, credential_id, public_key, sign_count, name, created_at, last_used_at`
+
+func scanPasskey(row pgx.Row) (models.Passkey, error) {
+	var p models.Passkey
+	err := row.Scan(&p.ID, &p.AdminID, &p.CredentialID, &p.PublicKey, &p.SignCount, &p.Name, &p.CreatedAt, &p.LastUsedAt)
+	return p, err
+}
+
+// CreatePasskey stores a newly registered passkey for a user.
+func CreatePasskey(adminID int, credentialID, publicKey []byte, signCount uint32, name string) (models.Passkey, error) {
+	p, err := scanPasskey(database.Pool.QueryRow(
+		context.Background(),
+		`INSERT INTO passkeys (admin_id, credential_id, public_key, sign_count, name)
+						VALUES ($1, $2, $3, $4, $5)
+						RETURNING `+passkeyColumns,
+		adminID, credentialID, publicKey, int64(signCount), name,
+	))
+	if err != nil {
+		return models.Passkey{}, fmt.Errorf("error creating passkey: %w", err)
+	}
+	return p, nil
+}
+
+// ListPasskeys returns a user's passkeys, oldest first.
+func ListPasskeys(adminID int) ([]models.Passkey, error) {
+	rows, err := database.Pool.Query(
+		context.Background(),
+		`SELECT `+passkeyColumns+` FROM passkeys WHERE admin_id = $1 ORDER BY created_at`,
+		adminID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying passkeys: %w", err)
+	}
+	defer rows.Close()
+
+	var passkeys []models.Passkey
+	for rows.Next() {
+		p, err := scanPasskey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing passkey: %w", err)
+		}
+		passkeys = append(passkeys, p)
+	}
+
+	return passkeys, nil
+}
+
+// GetPasskeyByCredentialID finds the passkey an authenticator signed in
+// with.
+func GetPasskeyByCredentialID(credentialID []byte) (models.Passkey, error) {
+	p, err := scanPasskey(database.Pool.QueryRow(
+		context.Background(),
+		`SELECT `+passkeyColumns+` FROM passkeys WHERE credential_id = $1`,
+		credentialID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Passkey{}, ErrPasskeyNotFound
+	}
+	if err != nil {
+		return models.Passkey{}, fmt.Errorf("error loading passkey: %w", err)
+	}
+	return p, nil
+}
+
+// UsePasskey records a sign-in with a passkey and the signature counter its
+// authenticator reported.
+func UsePasskey(id int, signCount uint32) error {
+	_, err := database.Pool.Exec(
+		context.Background(),
+		`UPDATE passkeys SET sign_count=$1, last_used_at=NOW() WHERE id=$2`,
+		int64(signCount), id,
+	)
+	if err != nil {
+		return fmt.Errorf("error updating passkey: %w", err)
+	}
+	return nil
+}
+
+// DeletePasskey removes one of a user's passkeys.
+func DeletePasskey(id, adminID int) error {
+	tag, err := database.Pool.Exec(
+		context.Background(),
+		`DELETE FROM passkeys WHERE id=$1 AND admin_id=$2`,
+		id, adminID,
+	)
+	if err != nil {
+		return fmt.Errorf("error deleting passkey: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrPasskeyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package queries
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+func TestPasskeys(t *testing.T) {
+	admin, err := CreateAdmin("passkeys-test@example.com", "hash", models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("Error creating admin: %v", err)
+	}
+	t.Cleanup(func() {
+		DeleteAdmin(admin.ID)
+	})
+
+	created, err := CreatePasskey(admin.ID, []byte("cred-1"), []byte("key"), 3, "Laptop")
+	if err != nil {
+		t.Fatalf("Error creating passkey: %v", err)
+	}
+
+	found, err := GetPasskeyByCredentialID([]byte("cred-1"))
+	if err != nil || found.ID != created.ID || found.AdminID != admin.ID || found.SignCount != 3 {
+		t.Fatalf("expected to find the passkey by credential ID, got %+v, %v", found, err)
+	}
+	if _, err := GetPasskeyByCredentialID([]byte("nope")); !errors.Is(err, ErrPasskeyNotFound) {
+		t.Errorf("expected ErrPasskeyNotFound, got %v", err)
+	}
+
+	if err := UsePasskey(created.ID, 4); err != nil {
+		t.Fatalf("Error using passkey: %v", err)
+	}
+	passkeys, _ := ListPasskeys(admin.ID)
+	if len(passkeys) != 1 || passkeys[0].SignCount != 4 || passkeys[0].LastUsedAt == nil {
+		t.Errorf("expected the sign-in to be recorded, got %+v", passkeys)
+	}
+
+	if err := DeletePasskey(created.ID, admin.ID+1); !errors.Is(err, ErrPasskeyNotFound) {
+		t.Errorf("expected another user's delete to fail, got %v", err)
+	}
+	if err := DeletePasskey(created.ID, admin.ID); err != nil {
+		t.Errorf("Error deleting passkey: %v", err)
+	}
+}
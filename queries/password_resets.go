@@ -0,0 +1,109 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrResetTokenInvalid is returned for a password reset token that doesn't
+// exist, has expired or has already been used.
+var ErrResetTokenInvalid = errors.New("password reset link is invalid or has expired")
+
+// CreatePasswordReset stores a reset token's hash for a user, valid for ttl.
+// Earlier unused tokens for the user stop working, so only the newest
+// emailed link does.
+func CreatePasswordReset(adminID int, tokenHash string, ttl time.Duration) error {
+	return withTx(func(tx pgx.Tx) error {
+		ctx := context.Background()
+		_, err := tx.Exec(ctx,
+			`DELETE FROM password_resets WHERE admin_id=$1 AND used_at IS NULL`,
+			adminID,
+		)
+		if err != nil {
+			return fmt.Errorf("error replacing password reset: %w", err)
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO password_resets (admin_id, token_hash, expires_at)
+							VALUES ($1, $2, NOW() + $3::interval)`,
+			adminID, tokenHash, ttl,
+		)
+		if err != nil {
+			return fmt.Errorf("error creating password reset: %w", err)
+		}
+		return nil
+	})
+}
+
+// PasswordResetRequestedSince reports whether the user has asked for a reset
+// since the given time, so repeated requests don't flood their inbox.
+func PasswordResetRequestedSince(adminID int, since time.Time) (bool, error) {
+	var exists bool
+	err := database.Pool.QueryRow(
+		context.Background(),
+		`SELECT EXISTS(SELECT 1 FROM password_resets WHERE admin_id=$1 AND created_at > $2)`,
+		adminID, since,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking password resets: %w", err)
+	}
+	return exists, nil
+}
+
+// GetPasswordResetAdmin returns the user a valid reset token is for, without
+// using it up.
+func GetPasswordResetAdmin(tokenHash string) (int, error) {
+	var adminID int
+	err := database.Pool.QueryRow(
+		context.Background(),
+		`SELECT admin_id FROM password_resets
+						WHERE token_hash=$1 AND used_at IS NULL AND expires_at > NOW()`,
+		tokenHash,
+	).Scan(&adminID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrResetTokenInvalid
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error loading password reset: %w", err)
+	}
+	return adminID, nil
+}
+
+// ResetPassword uses up a valid reset token and sets its user's password,
+// returning the user's ID. Using the token and changing the password happen
+// together, so a token can't be used twice even by concurrent requests.
+func ResetPassword(tokenHash, hashedPassword string) (int, error) {
+	var adminID int
+	err := withTx(func(tx pgx.Tx) error {
+		ctx := context.Background()
+		err := tx.QueryRow(ctx,
+			`UPDATE password_resets SET used_at=NOW()
+							WHERE token_hash=$1 AND used_at IS NULL AND expires_at > NOW()
+							RETURNING admin_id`,
+			tokenHash,
+		).Scan(&adminID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrResetTokenInvalid
+		}
+		if err != nil {
+			return fmt.Errorf("error using password reset: %w", err)
+		}
+
+		_, err = tx.Exec(ctx,
+			`UPDATE admins SET encrypted_password=$1, updated_at=NOW() WHERE id=$2`,
+			hashedPassword, adminID,
+		)
+		if err != nil {
+			return fmt.Errorf("error resetting password: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return adminID, nil
+}
@@ -0,0 +1,50 @@
+package queries
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+func TestPasswordReset(t *testing.T) {
+	admin, err := CreateAdmin("password-reset-test@example.com", "old-hash", models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("Error creating admin: %v", err)
+	}
+	t.Cleanup(func() {
+		DeleteAdmin(admin.ID)
+	})
+
+	if err := CreatePasswordReset(admin.ID, "first", time.Hour); err != nil {
+		t.Fatalf("Error creating reset: %v", err)
+	}
+	if err := CreatePasswordReset(admin.ID, "second", time.Hour); err != nil {
+		t.Fatalf("Error creating reset: %v", err)
+	}
+	if _, err := GetPasswordResetAdmin("first"); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Errorf("expected a newer request to replace the older token, got %v", err)
+	}
+	if id, err := GetPasswordResetAdmin("second"); err != nil || id != admin.ID {
+		t.Fatalf("expected the token to be valid for %d, got %d, %v", admin.ID, id, err)
+	}
+	if requested, _ := PasswordResetRequestedSince(admin.ID, time.Now().Add(-time.Minute)); !requested {
+		t.Error("expected the recent request to be found")
+	}
+
+	if id, err := ResetPassword("second", "new-hash"); err != nil || id != admin.ID {
+		t.Fatalf("expected the reset to succeed, got %d, %v", id, err)
+	}
+	if updated, _ := GetAdminByID(admin.ID); updated.EncryptedPassword != "new-hash" {
+		t.Errorf("expected the password to change, got %q", updated.EncryptedPassword)
+	}
+	if _, err := ResetPassword("second", "again"); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Errorf("expected a used token to be rejected, got %v", err)
+	}
+
+	CreatePasswordReset(admin.ID, "expired", -time.Minute)
+	if _, err := ResetPassword("expired", "again"); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Errorf("expected an expired token to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+func GetPostImages(postID int) ([]models.PostImage, error) {
+	rows, err := database.Pool.Query(
+		context.Background(),
+		`SELECT id, post_id, url, caption, alt_text, position, created_at
+						FROM post_images WHERE post_id = $1
+						ORDER BY position, id`,
+		postID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying post images: %w", err)
+	}
+	defer rows.Close()
+
+	var images []models.PostImage
+	for rows.Next() {
+		var img models.PostImage
+		err := rows.Scan(
+			&img.ID, &img.PostID, &img.URL, &img.Caption, &img.AltText,
+			&img.Position, &img.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing post image: %w", err)
+		}
+		images = append(images, img)
+	}
+
+	return images, nil
+}
+
+// CreatePostImage appends an image to the end of a post's gallery.
+func CreatePostImage(postID int, url, caption, altText string) (models.PostImage, error) {
+	var img models.PostImage
+	err := database.Pool.QueryRow(
+		context.Background(),
+		`INSERT INTO post_images (post_id, url, caption, alt_text, position)
+						VALUES ($1, $2, $3, $4,
+							(SELECT COALESCE(MAX(position), 0) + 1 FROM post_images WHERE post_id = $1))
+						RETURNING id, post_id, url, caption, alt_text, position, created_at`,
+		postID, url, caption, altText,
+	).Scan(
+		&img.ID, &img.PostID, &img.URL, &img.Caption, &img.AltText,
+		&img.Position, &img.CreatedAt,
+	)
+	if err != nil {
+		return models.PostImage{}, fmt.Errorf("error creating post image: %w", err)
+	}
+	return img, nil
+}
+
+func UpdatePostImage(id int, caption, altText string, position int) error {
+	_, err := database.Pool.Exec(
+		context.Background(),
+		`UPDATE post_images SET caption=$1, alt_text=$2, position=$3 WHERE id=$4`,
+		caption, altText, position, id,
+	)
+	if err != nil {
+		return fmt.Errorf("error updating post image: %w", err)
+	}
+	return nil
+}
+
+func DeletePostImage(id int) error {
+	_, err := database.Pool.Exec(
+		context.Background(),
+		`DELETE FROM post_images WHERE id=$1`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("error deleting post image: %w", err)
+	}
+	return nil
+}
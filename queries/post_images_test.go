@@ -0,0 +1,41 @@
+package queries
+
+import "testing"
+
+func TestPostImagesAppendInOrder(t *testing.T) {
+	post, err := CreatePost("Gallery Test", "tag", "body", "gallery-test", "", nil, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+
+	t.Cleanup(func() {
+		DeletePost(post.ID)
+	})
+
+	first, err := CreatePostImage(post.ID, "/static/uploads/1.jpg", "First", "")
+	if err != nil {
+		t.Fatalf("Error creating image: %v", err)
+	}
+	second, err := CreatePostImage(post.ID, "/static/uploads/2.jpg", "Second", "")
+	if err != nil {
+		t.Fatalf("Error creating image: %v", err)
+	}
+	if second.Position <= first.Position {
+		t.Errorf("expected second image after first, got positions %d and %d", first.Position, second.Position)
+	}
+
+	if err := UpdatePostImage(second.ID, "Second", "alt", 0); err != nil {
+		t.Fatalf("Error updating image: %v", err)
+	}
+
+	images, err := GetPostImages(post.ID)
+	if err != nil {
+		t.Fatalf("Error fetching images: %v", err)
+	}
+	if len(images) != 2 {
+		t.Fatalf("expected 2 images, got %d", len(images))
+	}
+	if images[0].ID != second.ID {
+		t.Errorf("expected reordered image first, got id %d", images[0].ID)
+	}
+}
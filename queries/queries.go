@@ -2,17 +2,43 @@ package queries
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/hiimtaylorjones/hiimtaylor-go/database"
 	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// postColumns lists the columns read by scanPost, in scan order. Every query
+// returning posts selects (or RETURNs) exactly these.
+const postColumns = `id, title, tagline, body, slug, COALESCE(author_id, 0), status, publish_at, COALESCE(reviewer_id, 0), banner_image_url, meta, layout, version, created_at, updated_at, deleted_at`
+
+// publicCondition matches the posts visitors may see: not trashed, and
+// either published or scheduled with a publish time that has passed.
+const publicCondition = `deleted_at IS NULL AND (status = 'published' OR (status = 'scheduled' AND publish_at <= NOW()))`
+
+// publicOrder lists public posts newest first by the date visitors see
+// (models.Post.PublishedAt), so scheduled posts aren't back-dated to their
+// draft.
+const publicOrder = `COALESCE(publish_at, created_at) DESC, id DESC`
+
+func scanPost(row pgx.Row) (models.Post, error) {
+	var p models.Post
+	err := row.Scan(
+		&p.ID, &p.Title, &p.Tagline, &p.Body, &p.Slug, &p.AuthorID,
+		&p.Status, &p.PublishAt, &p.ReviewerID, &p.BannerImageURL, &p.Metadata, &p.Layout, &p.Version, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt,
+	)
+	return p, err
+}
+
 func CountPublishedPosts() (int, error) {
 	var count int
 	err := database.Pool.QueryRow(
 		context.Background(),
-		`SELECT COUNT(*) FROM posts WHERE published = TRUE`,
+		`SELECT COUNT(*) FROM posts WHERE `+publicCondition,
 	).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("error counting posts: %w", err)
@@ -22,9 +48,9 @@ func CountPublishedPosts() (int, error) {
 
 func GetPublishedPosts(page, perPage int) ([]models.Post, error) {
 	offset := (page - 1) * perPage
-	query := `SELECT id, title, tagline, body, slug, banner_image_url, published, created_at, updated_at 
-						FROM posts WHERE published = TRUE 
-						ORDER BY created_at DESC
+	query := `SELECT ` + postColumns + `
+						FROM posts WHERE ` + publicCondition + `
+						ORDER BY ` + publicOrder + `
 						LIMIT $1 OFFSET $2`
 
 	rows, err := database.Pool.Query(
@@ -41,11 +67,7 @@ func GetPublishedPosts(page, perPage int) ([]models.Post, error) {
 
 	var posts []models.Post
 	for rows.Next() {
-		var p models.Post
-		err := rows.Scan(
-			&p.ID, &p.Title, &p.Tagline, &p.Body, &p.Slug,
-			&p.BannerImageURL, &p.Published, &p.CreatedAt, &p.UpdatedAt,
-		)
+		p, err := scanPost(rows)
 		if err != nil {
 			return nil, fmt.Errorf("error parsing post: %w", err)
 		}
@@ -56,17 +78,13 @@ func GetPublishedPosts(page, perPage int) ([]models.Post, error) {
 }
 
 func GetPostBySlug(slug string) (models.Post, error) {
-	var p models.Post
-	query := `SELECT id, title, tagline, body, slug, published, banner_image_url, created_at, updated_at 
-						FROM posts WHERE slug = $1`
-	err := database.Pool.QueryRow(
+	query := `SELECT ` + postColumns + `
+						FROM posts WHERE slug = $1 AND deleted_at IS NULL`
+	p, err := scanPost(database.Pool.QueryRow(
 		context.Background(),
 		query,
 		slug,
-	).Scan(
-		&p.ID, &p.Title, &p.Tagline, &p.Body, &p.Slug,
-		&p.Published, &p.BannerImageURL, &p.CreatedAt, &p.UpdatedAt,
-	)
+	))
 
 	if err != nil {
 		return models.Post{}, fmt.Errorf("post not found: %w", err)
@@ -74,21 +92,48 @@ func GetPostBySlug(slug string) (models.Post, error) {
 	return p, nil
 }
 
-func CreatePost(title, tagline, body, slug, bannerImageURL string, published bool) (models.Post, error) {
-	var p models.Post
-	query := `
-		INSERT INTO posts (title, tagline, body, slug, published, banner_image_url) 
-			VALUES($1, $2, $3, $4, $5, $6) 
-			RETURNING id, title, tagline, body, slug, published, banner_image_url, created_at, updated_at
-	`
+// GetPostByID returns a post of any status that is not in the trash.
+func GetPostByID(id int) (models.Post, error) {
+	p, err := scanPost(database.Pool.QueryRow(
+		context.Background(),
+		`SELECT `+postColumns+` FROM posts WHERE id = $1 AND deleted_at IS NULL`,
+		id,
+	))
+	if err != nil {
+		return models.Post{}, fmt.Errorf("post not found: %w", err)
+	}
+	return p, nil
+}
+
+// SlugExists reports whether any post, including trashed ones, uses slug.
+func SlugExists(slug string) (bool, error) {
+	var exists bool
 	err := database.Pool.QueryRow(
+		context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM posts WHERE slug = $1)`,
+		slug,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking slug: %w", err)
+	}
+	return exists, nil
+}
+
+// CreatePost inserts a new post written by authorID (0 for none). Posts
+// always start as drafts; status changes go through TransitionPost.
+func CreatePost(title, tagline, body, slug, bannerImageURL string, meta models.PostMeta, layout string, authorID int) (models.Post, error) {
+	if meta == nil {
+		meta = models.PostMeta{}
+	}
+	query := `
+		INSERT INTO posts (title, tagline, body, slug, banner_image_url, meta, layout, author_id) 
+			VALUES($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0)) 
+			RETURNING ` + postColumns
+	p, err := scanPost(database.Pool.QueryRow(
 		context.Background(),
 		query,
-		title, tagline, body, slug, published, bannerImageURL,
-	).Scan(
-		&p.ID, &p.Title, &p.Tagline, &p.Body, &p.Slug,
-		&p.Published, &p.BannerImageURL, &p.CreatedAt, &p.UpdatedAt,
-	)
+		title, tagline, body, slug, bannerImageURL, meta, layout, authorID,
+	))
 
 	if err != nil {
 		return models.Post{}, fmt.Errorf("error creating post: %w", err)
@@ -96,29 +141,57 @@ func CreatePost(title, tagline, body, slug, bannerImageURL string, published boo
 	return p, nil
 }
 
-func UpdatePost(id int, title, tagline, body, bannerImageURL string, published bool) (models.Post, error) {
-	var p models.Post
+// ErrConflict is returned by UpdatePost when the post changed since the
+// version the editor loaded.
+var ErrConflict = errors.New("post was changed by someone else")
+
+// UpdatePost saves an edit made against the given version of the post. If
+// the post has moved on since then it returns ErrConflict and changes
+// nothing.
+func UpdatePost(id, version int, title, tagline, body, bannerImageURL string, meta models.PostMeta, layout string) (models.Post, error) {
+	if meta == nil {
+		meta = models.PostMeta{}
+	}
 	query := `
-		UPDATE posts SET title=$1, tagline=$2, body=$3, published=$4, banner_image_url=$5, updated_at=NOW()
-			WHERE id=$6
-			RETURNING id, title, tagline, body, slug, published, banner_image_url, created_at, updated_at
-	`
+		UPDATE posts SET title=$1, tagline=$2, body=$3, banner_image_url=$4, meta=$5, layout=$6,
+				version=version+1, updated_at=NOW()
+			WHERE id=$7 AND version=$8 AND deleted_at IS NULL
+			RETURNING ` + postColumns
 
-	err := database.Pool.QueryRow(
+	p, err := scanPost(database.Pool.QueryRow(
 		context.Background(),
 		query,
-		title, tagline, body, published, bannerImageURL, id,
-	).Scan(
-		&p.ID, &p.Title, &p.Tagline, &p.Body, &p.Slug,
-		&p.Published, &p.BannerImageURL, &p.CreatedAt, &p.UpdatedAt,
-	)
+		title, tagline, body, bannerImageURL, meta, layout, id, version,
+	))
 
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Post{}, ErrConflict
+	}
 	if err != nil {
 		return models.Post{}, fmt.Errorf("error updating post: %w", err)
 	}
 	return p, nil
 }
 
+// DuplicatePost copies a post's title, tagline, body, banner, metadata and
+// layout into a new draft with the given slug, written by authorID.
+func DuplicatePost(id int, slug string, authorID int) (models.Post, error) {
+	p, err := scanPost(database.Pool.QueryRow(
+		context.Background(),
+		`INSERT INTO posts (title, tagline, body, slug, banner_image_url, meta, layout, author_id)
+			SELECT title, tagline, body, $2, banner_image_url, meta, layout, NULLIF($3, 0)
+				FROM posts WHERE id = $1 AND deleted_at IS NULL
+			RETURNING `+postColumns,
+		id, slug, authorID,
+	))
+	if err != nil {
+		return models.Post{}, fmt.Errorf("error duplicating post: %w", err)
+	}
+	return p, nil
+}
+
+// DeletePost removes a post row outright. Admin deletes go through TrashPost;
+// this is for purging and test cleanup.
 func DeletePost(id int) error {
 	_, err := database.Pool.Exec(
 		context.Background(),
@@ -131,13 +204,129 @@ func DeletePost(id int) error {
 	return nil
 }
 
-func GetAdminByEmail(email string) (models.Admin, error) {
-	var a models.Admin
+// TrashPost soft-deletes a post. Trashed posts are hidden from every public
+// query until RestorePost or a purge.
+func TrashPost(id int) error {
+	_, err := database.Pool.Exec(
+		context.Background(),
+		`UPDATE posts SET deleted_at=NOW() WHERE id=$1 AND deleted_at IS NULL`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("error trashing post: %w", err)
+	}
+	return nil
+}
+
+func RestorePost(id int) error {
+	_, err := database.Pool.Exec(
+		context.Background(),
+		`UPDATE posts SET deleted_at=NULL WHERE id=$1`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("error restoring post: %w", err)
+	}
+	return nil
+}
+
+// PurgePost permanently deletes a post, but only one already in the trash.
+// It returns the uploaded files (banner, gallery images and attachments)
+// that nothing else refers to any more, for the caller to remove.
+func PurgePost(id int) ([]string, error) {
+	_, files, err := purgePosts(`id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("error purging post: %w", err)
+	}
+	return files, nil
+}
+
+// PurgeTrashedOlderThan permanently deletes posts that have been in the
+// trash longer than retention, reporting how many were removed and, as
+// PurgePost does, which of their files are no longer used. The cutoff is
+// computed in SQL so it agrees with the NOW() that set deleted_at.
+func PurgeTrashedOlderThan(retention time.Duration) (int64, []string, error) {
+	n, files, err := purgePosts(`deleted_at < NOW() - $1::interval`, retention)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error purging trash: %w", err)
+	}
+	return n, files, nil
+}
+
+// purgePosts deletes the trashed posts matching condition. A copied post
+// shares its original's banner, so files still referenced by another post,
+// image or attachment are left out of the list it returns. The statement's
+// parts all see the rows as they were before the delete, so the purged
+// posts' images and attachments can still be read after their cascade.
+func purgePosts(condition string, args ...any) (int64, []string, error) {
+	var n int64
+	var files []string
 	err := database.Pool.QueryRow(
 		context.Background(),
-		`SELECT id, email, encrypted_password FROM admins WHERE email = $1`,
+		`WITH purged AS (
+			DELETE FROM posts WHERE deleted_at IS NOT NULL AND `+condition+`
+			RETURNING id, banner_image_url
+		), files AS (
+			SELECT banner_image_url AS url FROM purged WHERE banner_image_url <> ''
+			UNION SELECT url FROM post_images WHERE post_id IN (SELECT id FROM purged)
+			UNION SELECT url FROM post_attachments WHERE post_id IN (SELECT id FROM purged)
+		)
+		SELECT
+			(SELECT COUNT(*) FROM purged),
+			ARRAY(SELECT url FROM files WHERE
+				NOT EXISTS (SELECT 1 FROM posts WHERE banner_image_url = files.url AND id NOT IN (SELECT id FROM purged))
+				AND NOT EXISTS (SELECT 1 FROM post_images WHERE url = files.url AND post_id NOT IN (SELECT id FROM purged))
+				AND NOT EXISTS (SELECT 1 FROM post_attachments WHERE url = files.url AND post_id NOT IN (SELECT id FROM purged)))`,
+		args...,
+	).Scan(&n, &files)
+	return n, files, err
+}
+
+func GetTrashedPosts() ([]models.Post, error) {
+	rows, err := database.Pool.Query(
+		context.Background(),
+		`SELECT `+postColumns+`
+						FROM posts WHERE deleted_at IS NOT NULL
+						ORDER BY deleted_at DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying trash: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []models.Post
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing post: %w", err)
+		}
+		posts = append(posts, p)
+	}
+
+	return posts, nil
+}
+
+// adminColumns lists the columns read by scanAdmin, in scan order.
+const adminColumns = `id, email, encrypted_password, role, name, COALESCE(slug, ''), bio, avatar_url, disabled_at, COALESCE(totp_secret, ''), totp_enabled_at, created_at, updated_at`
+
+func scanAdmin(row pgx.Row) (models.Admin, error) {
+	var a models.Admin
+	err := row.Scan(
+		&a.ID, &a.Email, &a.EncryptedPassword, &a.Role,
+		&a.Name, &a.Slug, &a.Bio, &a.AvatarURL, &a.DisabledAt, &a.TOTPSecret, &a.TOTPEnabledAt,
+		&a.CreatedAt, &a.UpdatedAt,
+	)
+	return a, err
+}
+
+// GetAdminByEmail finds a user by email address, ignoring case: providers
+// and people don't always capitalize an address the way it was stored.
+func GetAdminByEmail(email string) (models.Admin, error) {
+	a, err := scanAdmin(database.Pool.QueryRow(
+		context.Background(),
+		`SELECT `+adminColumns+` FROM admins WHERE lower(email) = lower($1)`,
 		email,
-	).Scan(&a.ID, &a.Email, &a.EncryptedPassword)
+	))
 
 	if err != nil {
 		return models.Admin{}, fmt.Errorf("admin not found: %w", err)
@@ -145,29 +334,89 @@ func GetAdminByEmail(email string) (models.Admin, error) {
 	return a, nil
 }
 
-func CreateAdmin(email, hashedPassword string) (models.Admin, error) {
-	var a models.Admin
-	err := database.Pool.QueryRow(
+func GetAdminByID(id int) (models.Admin, error) {
+	a, err := scanAdmin(database.Pool.QueryRow(
 		context.Background(),
-		`INSERT INTO admins (email, encrypted_password)
-						VALUES ($1, $2)
-						RETURNING id, email, encrypted_password`,
-		email, hashedPassword,
-	).Scan(&a.ID, &a.Email, &a.EncryptedPassword)
+		`SELECT `+adminColumns+` FROM admins WHERE id = $1`,
+		id,
+	))
 	if err != nil {
-		return models.Admin{}, fmt.Errorf("error creating admin: %w", err)
+		return models.Admin{}, fmt.Errorf("admin not found: %w", err)
 	}
 	return a, nil
 }
 
-func DeleteAdmin(email string) bool {
-	_, err := database.Pool.Exec(
+// CreateAdmin adds a user with the given role.
+func CreateAdmin(email, hashedPassword string, role models.Role) (models.Admin, error) {
+	a, err := scanAdmin(database.Pool.QueryRow(
 		context.Background(),
-		`DELETE FROM admins WHERE email=$1`,
-		email,
-	)
+		`INSERT INTO admins (email, encrypted_password, role)
+						VALUES ($1, $2, $3)
+						RETURNING `+adminColumns,
+		email, hashedPassword, string(role),
+	))
 	if err != nil {
-		return true
+		return models.Admin{}, adminError("creating admin", err)
+	}
+	return a, nil
+}
+
+// ErrAdminNotFound is returned when changing a user that doesn't exist.
+var ErrAdminNotFound = errors.New("admin not found")
+
+// ErrEmailTaken is returned when another user already has the email address.
+var ErrEmailTaken = errors.New("email address already in use")
+
+// adminError maps a unique violation on admins.email, exact or ignoring
+// case, to ErrEmailTaken.
+func adminError(action string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" &&
+		(pgErr.ConstraintName == "admins_email_key" || pgErr.ConstraintName == "admins_email_lower_key") {
+		return ErrEmailTaken
 	}
-	return false
+	return fmt.Errorf("error %s: %w", action, err)
+}
+
+// execAdmin runs an UPDATE or DELETE on one admin row, returning
+// ErrAdminNotFound when no row matched.
+func execAdmin(action, query string, args ...any) error {
+	tag, err := database.Pool.Exec(context.Background(), query, args...)
+	if err != nil {
+		return adminError(action, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAdminNotFound
+	}
+	return nil
+}
+
+func DeleteAdmin(id int) error {
+	return execAdmin("deleting admin", `DELETE FROM admins WHERE id=$1`, id)
+}
+
+func UpdateAdminEmail(id int, email string) error {
+	return execAdmin("updating email",
+		`UPDATE admins SET email=$1, updated_at=NOW() WHERE id=$2`, email, id)
+}
+
+func UpdateAdminPassword(id int, hashedPassword string) error {
+	return execAdmin("updating password",
+		`UPDATE admins SET encrypted_password=$1, updated_at=NOW() WHERE id=$2`, hashedPassword, id)
+}
+
+// RehashAdminPassword swaps in a fresh hash of the same password. It only
+// applies while the stored hash is still oldHash, so it can't undo a password
+// change that happened in the meantime, and it leaves updated_at alone since
+// nothing the user chose has changed.
+func RehashAdminPassword(id int, oldHash, newHash string) error {
+	return execAdmin("rehashing password",
+		`UPDATE admins SET encrypted_password=$1 WHERE id=$2 AND encrypted_password=$3`, newHash, id, oldHash)
+}
+
+// SetAdminDisabled disables or re-enables a user's account.
+func SetAdminDisabled(id int, disabled bool) error {
+	return execAdmin("updating admin",
+		`UPDATE admins SET disabled_at = CASE WHEN $1 THEN COALESCE(disabled_at, NOW()) END, updated_at=NOW()
+			WHERE id=$2`, disabled, id)
 }
@@ -1,6 +1,7 @@
 package queries
 
 import (
+	"errors"
 	"log"
 	"os"
 	"testing"
@@ -8,6 +9,7 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
 	"github.com/joho/godotenv"
 )
 
@@ -22,7 +24,7 @@ func TestMain(m *testing.M) {
 }
 
 func TestCreatePost(t *testing.T) {
-	post, err := CreatePost("Queries Test", "tagline", "body", "queries-test", "", false)
+	post, err := CreatePost("Queries Test", "tagline", "body", "queries-test", "", nil, "", 0)
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
@@ -40,19 +42,19 @@ func TestCreatePost(t *testing.T) {
 }
 
 func TestUpdatePost(t *testing.T) {
-	post, err := CreatePost("Test Post", "tag", "body", "test-post", "", false)
+	post, err := CreatePost("Test Post", "tag", "body", "test-post", "", nil, "", 0)
 	if err != nil {
 		t.Fatalf("Error creating post: %v", err)
 	}
 
-	post, err = UpdatePost(post.ID, "Test Post", "tag", "body", "", true)
+	post, err = UpdatePost(post.ID, post.Version, "Test Post", "new tag", "body", "", nil, "")
 
 	if err != nil {
 		t.Fatalf("Error updating post: %v", err)
 	}
 
-	if post.Published != true {
-		t.Errorf("expected post update to make published true")
+	if post.Tagline != "new tag" {
+		t.Errorf("expected post update to change the tagline, got %q", post.Tagline)
 	}
 
 	t.Cleanup(func() {
@@ -60,10 +62,168 @@ func TestUpdatePost(t *testing.T) {
 	})
 }
 
+func TestUpdatePost_StaleVersionConflicts(t *testing.T) {
+	post, err := CreatePost("Conflict Test", "tag", "body", "conflict-test", "", nil, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+
+	t.Cleanup(func() {
+		DeletePost(post.ID)
+	})
+
+	if _, err := UpdatePost(post.ID, post.Version, "First tab", "tag", "body", "", nil, ""); err != nil {
+		t.Fatalf("Error updating post: %v", err)
+	}
+
+	_, err = UpdatePost(post.ID, post.Version, "Second tab", "tag", "body", "", nil, "")
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict for a stale version, got: %v", err)
+	}
+
+	current, err := GetPostBySlug(post.Slug)
+	if err != nil {
+		t.Fatalf("Error fetching post: %v", err)
+	}
+	if current.Title != "First tab" {
+		t.Errorf("expected first save to survive, got title %q", current.Title)
+	}
+}
+
+func TestPostMetaRoundTrip(t *testing.T) {
+	meta := models.PostMeta{"canonical_url": "https://example.com/original"}
+	post, err := CreatePost("Meta Test", "tag", "body", "meta-test", "", meta, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+
+	t.Cleanup(func() {
+		DeletePost(post.ID)
+	})
+
+	fetched, err := GetPostBySlug(post.Slug)
+	if err != nil {
+		t.Fatalf("Error fetching post: %v", err)
+	}
+	if got := fetched.Meta("canonical_url"); got != "https://example.com/original" {
+		t.Errorf("expected canonical_url to round trip, got %q", got)
+	}
+}
+
+func TestTrashRestoreFlow(t *testing.T) {
+	post, err := CreatePost("Trash Test", "tag", "body", "trash-test", "", nil, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+
+	t.Cleanup(func() {
+		DeletePost(post.ID)
+	})
+
+	if err := TrashPost(post.ID); err != nil {
+		t.Fatalf("Error trashing post: %v", err)
+	}
+	if _, err := GetPostBySlug(post.Slug); err == nil {
+		t.Error("expected trashed post to be hidden from GetPostBySlug")
+	}
+
+	trashed, err := GetTrashedPosts()
+	if err != nil {
+		t.Fatalf("Error fetching trash: %v", err)
+	}
+	found := false
+	for _, p := range trashed {
+		if p.ID == post.ID {
+			found = p.DeletedAt != nil
+		}
+	}
+	if !found {
+		t.Error("expected trashed post to be listed with a DeletedAt")
+	}
+
+	if err := RestorePost(post.ID); err != nil {
+		t.Fatalf("Error restoring post: %v", err)
+	}
+	if _, err := GetPostBySlug(post.Slug); err != nil {
+		t.Errorf("expected restored post to be visible, got: %v", err)
+	}
+}
+
+func TestDuplicatePost(t *testing.T) {
+	meta := models.PostMeta{"series": "roundup"}
+	post, err := CreatePost("Roundup", "tag", "body", "duplicate-roundup", "/static/uploads/banner.jpg", meta, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+
+	copied, err := DuplicatePost(post.ID, "duplicate-roundup-copy", 0)
+	if err != nil {
+		t.Fatalf("Error duplicating post: %v", err)
+	}
+
+	t.Cleanup(func() {
+		DeletePost(post.ID)
+		DeletePost(copied.ID)
+	})
+
+	if copied.ID == post.ID || copied.Slug != "duplicate-roundup-copy" {
+		t.Errorf("expected a new post with the given slug, got id %d slug %q", copied.ID, copied.Slug)
+	}
+	if copied.Status != models.StatusDraft {
+		t.Errorf("expected duplicate to be a draft, got %q", copied.Status)
+	}
+	if copied.Body != post.Body || copied.BannerImageURL != post.BannerImageURL || copied.Meta("series") != "roundup" {
+		t.Errorf("expected body, banner and metadata to be copied, got %+v", copied)
+	}
+
+	exists, err := SlugExists("duplicate-roundup-copy")
+	if err != nil || !exists {
+		t.Errorf("expected duplicate slug to exist, got %v (err %v)", exists, err)
+	}
+}
+
+func TestPurgePost_ReturnsUnusedFiles(t *testing.T) {
+	post, err := CreatePost("Purge", "tag", "body", "purge-files", "/static/uploads/purge-banner.jpg", nil, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+	copied, err := DuplicatePost(post.ID, "purge-files-copy", 0)
+	if err != nil {
+		t.Fatalf("Error duplicating post: %v", err)
+	}
+
+	t.Cleanup(func() {
+		DeletePost(post.ID)
+		DeletePost(copied.ID)
+	})
+
+	if _, err := CreateAttachment(post.ID, "/static/uploads/purge.pdf", "purge.pdf", "application/pdf", 10); err != nil {
+		t.Fatalf("Error creating attachment: %v", err)
+	}
+	if _, err := PurgePost(post.ID); err != nil {
+		t.Fatalf("Error purging live post: %v", err)
+	}
+	if _, err := GetPostByID(post.ID); err != nil {
+		t.Fatalf("expected a post outside the trash not to be purged, got: %v", err)
+	}
+
+	if err := TrashPost(post.ID); err != nil {
+		t.Fatalf("Error trashing post: %v", err)
+	}
+	files, err := PurgePost(post.ID)
+	if err != nil {
+		t.Fatalf("Error purging post: %v", err)
+	}
+	// The copy still shows the banner, so only the attachment can go.
+	if len(files) != 1 || files[0] != "/static/uploads/purge.pdf" {
+		t.Errorf("expected only the attachment to be unused, got %v", files)
+	}
+}
+
 func TestAdminCreateFetchFlow(t *testing.T) {
 	var password string = "my-secret-password"
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	admin, err := CreateAdmin("hello!@me.com", string(hash))
+	admin, err := CreateAdmin("hello!@me.com", string(hash), models.RoleAuthor)
 
 	if err != nil {
 		t.Fatalf("Error creating admin: %v", err)
@@ -80,6 +240,70 @@ func TestAdminCreateFetchFlow(t *testing.T) {
 	}
 
 	t.Cleanup(func() {
-		DeleteAdmin(admin.Email)
+		DeleteAdmin(admin.ID)
+	})
+}
+
+func TestGetAdminByEmail_IgnoresCase(t *testing.T) {
+	admin, err := CreateAdmin("case-test@example.com", "hash", models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("Error creating admin: %v", err)
+	}
+	t.Cleanup(func() { DeleteAdmin(admin.ID) })
+
+	found, err := GetAdminByEmail("Case-Test@Example.COM")
+	if err != nil || found.ID != admin.ID {
+		t.Errorf("expected the admin regardless of case, got %d, %v", found.ID, err)
+	}
+	if _, err := CreateAdmin("CASE-TEST@example.com", "hash", models.RoleAuthor); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("expected ErrEmailTaken for the same address in other case, got %v", err)
+	}
+}
+
+func TestAdminAccountChanges(t *testing.T) {
+	admin, err := CreateAdmin("account-test@example.com", "hash", models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("Error creating admin: %v", err)
+	}
+	other, err := CreateAdmin("account-other@example.com", "hash", models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("Error creating admin: %v", err)
+	}
+
+	t.Cleanup(func() {
+		DeleteAdmin(admin.ID)
+		DeleteAdmin(other.ID)
 	})
+
+	if err := UpdateAdminEmail(admin.ID, other.Email); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("expected ErrEmailTaken for a duplicate email, got: %v", err)
+	}
+	if _, err := CreateAdmin(other.Email, "hash", models.RoleAuthor); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("expected ErrEmailTaken creating a duplicate, got: %v", err)
+	}
+
+	if err := RehashAdminPassword(admin.ID, "hash", "new hash"); err != nil {
+		t.Errorf("Error rehashing password: %v", err)
+	}
+	if err := RehashAdminPassword(admin.ID, "hash", "newer hash"); !errors.Is(err, ErrAdminNotFound) {
+		t.Errorf("expected a stale rehash to be skipped, got: %v", err)
+	}
+	if rehashed, _ := GetAdminByID(admin.ID); rehashed.EncryptedPassword != "new hash" {
+		t.Errorf("expected the first rehash to stick, got %q", rehashed.EncryptedPassword)
+	}
+
+	if err := SetAdminDisabled(admin.ID, true); err != nil {
+		t.Fatalf("Error disabling admin: %v", err)
+	}
+	disabled, err := GetAdminByID(admin.ID)
+	if err != nil || !disabled.Disabled() {
+		t.Errorf("expected the admin to be disabled, got %+v (err %v)", disabled, err)
+	}
+
+	if err := DeleteAdmin(other.ID); err != nil {
+		t.Fatalf("Error deleting admin: %v", err)
+	}
+	if err := DeleteAdmin(other.ID); !errors.Is(err, ErrAdminNotFound) {
+		t.Errorf("expected ErrAdminNotFound deleting twice, got: %v", err)
+	}
 }
@@ -0,0 +1,95 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+// BulkTagPosts adds tag to the given posts and reports how many didn't
+// already have it. Trashed posts are skipped.
+func BulkTagPosts(ids []int, tag string) (int64, error) {
+	t, err := database.Pool.Exec(
+		context.Background(),
+		`INSERT INTO post_tags (post_id, tag)
+						SELECT id, $2 FROM posts WHERE id = ANY($1) AND deleted_at IS NULL
+						ON CONFLICT DO NOTHING`,
+		ids, tag,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error tagging posts: %w", err)
+	}
+	return t.RowsAffected(), nil
+}
+
+// BulkUntagPosts removes tag from the given posts and reports how many had
+// it.
+func BulkUntagPosts(ids []int, tag string) (int64, error) {
+	t, err := database.Pool.Exec(
+		context.Background(),
+		`DELETE FROM post_tags WHERE post_id = ANY($1) AND tag = $2`,
+		ids, tag,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error untagging posts: %w", err)
+	}
+	return t.RowsAffected(), nil
+}
+
+// GetTagsForPosts loads the tags of several posts in one query, keyed by
+// post ID and sorted by name.
+func GetTagsForPosts(postIDs []int) (map[int][]string, error) {
+	rows, err := database.Pool.Query(
+		context.Background(),
+		`SELECT post_id, tag FROM post_tags WHERE post_id = ANY($1) ORDER BY post_id, tag`,
+		postIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[int][]string)
+	for rows.Next() {
+		var postID int
+		var tag string
+		if err := rows.Scan(&postID, &tag); err != nil {
+			return nil, fmt.Errorf("error parsing tag: %w", err)
+		}
+		tags[postID] = append(tags[postID], tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error querying tags: %w", err)
+	}
+	return tags, nil
+}
+
+// GetPublishedPostsByTag returns a page of the public posts tagged tag,
+// newest first.
+func GetPublishedPostsByTag(tag string, page, perPage int) ([]models.Post, error) {
+	rows, err := database.Pool.Query(
+		context.Background(),
+		`SELECT `+postColumns+` FROM posts
+			WHERE id IN (SELECT post_id FROM post_tags WHERE tag = $1) AND `+publicCondition+`
+			ORDER BY `+publicOrder+`
+			LIMIT $2 OFFSET $3`,
+		tag, perPage, (page-1)*perPage,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying posts: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []models.Post
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing post: %w", err)
+		}
+		posts = append(posts, p)
+	}
+
+	return posts, nil
+}
@@ -0,0 +1,36 @@
+package queries
+
+import "testing"
+
+func TestGetPublishedPostsByTag(t *testing.T) {
+	episode, err := CreatePost("Tagged Episode", "tag", "body", "tagged-episode", "", nil, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+	draft, err := CreatePost("Tagged Draft", "tag", "body", "tagged-draft", "", nil, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+	other, err := CreatePost("Untagged Post", "tag", "body", "untagged-post", "", nil, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+	t.Cleanup(func() {
+		DeletePost(episode.ID)
+		DeletePost(draft.ID)
+		DeletePost(other.ID)
+	})
+	publish(t, episode.ID)
+	publish(t, other.ID)
+
+	if _, err := BulkTagPosts([]int{episode.ID, draft.ID}, "tagged-feed-test"); err != nil {
+		t.Fatalf("Error tagging posts: %v", err)
+	}
+	posts, err := GetPublishedPostsByTag("tagged-feed-test", 1, 10)
+	if err != nil {
+		t.Fatalf("Error fetching posts: %v", err)
+	}
+	if len(posts) != 1 || posts[0].ID != episode.ID {
+		t.Errorf("expected only the published tagged post, got %d posts", len(posts))
+	}
+}
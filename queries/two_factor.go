@@ -0,0 +1,118 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+	"github.com/jackc/pgx/v5"
+)
+
+// EnableTwoFactor turns on two-factor sign-in with secret, whose code for
+// step the user just confirmed, and replaces their recovery codes.
+func EnableTwoFactor(id int, secret string, step int64, codeHashes []string) error {
+	return withTx(func(tx pgx.Tx) error {
+		tag, err := tx.Exec(
+			context.Background(),
+			`UPDATE admins SET totp_secret=$1, totp_enabled_at=NOW(), totp_last_step=$2, updated_at=NOW()
+							WHERE id=$3`,
+			secret, step, id,
+		)
+		if err != nil {
+			return fmt.Errorf("error enabling two-factor: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrAdminNotFound
+		}
+		return replaceRecoveryCodes(tx, id, codeHashes)
+	})
+}
+
+// DisableTwoFactor turns off two-factor sign-in and deletes the user's
+// recovery codes.
+func DisableTwoFactor(id int) error {
+	return withTx(func(tx pgx.Tx) error {
+		tag, err := tx.Exec(
+			context.Background(),
+			`UPDATE admins SET totp_secret=NULL, totp_enabled_at=NULL, totp_last_step=0, updated_at=NOW()
+							WHERE id=$1`,
+			id,
+		)
+		if err != nil {
+			return fmt.Errorf("error disabling two-factor: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrAdminNotFound
+		}
+		return replaceRecoveryCodes(tx, id, nil)
+	})
+}
+
+// ReplaceRecoveryCodes discards the user's recovery codes, used or not, and
+// stores codeHashes in their place.
+func ReplaceRecoveryCodes(id int, codeHashes []string) error {
+	return withTx(func(tx pgx.Tx) error {
+		return replaceRecoveryCodes(tx, id, codeHashes)
+	})
+}
+
+func replaceRecoveryCodes(tx pgx.Tx, id int, codeHashes []string) error {
+	ctx := context.Background()
+	if _, err := tx.Exec(ctx, `DELETE FROM admin_recovery_codes WHERE admin_id=$1`, id); err != nil {
+		return fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+	for _, hash := range codeHashes {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO admin_recovery_codes (admin_id, code_hash) VALUES ($1, $2)`,
+			id, hash,
+		)
+		if err != nil {
+			return fmt.Errorf("error saving recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+// UseTOTPStep records that the user signed in with the code for step. It
+// reports false if that step, or a later one, was already used, so each code
+// works only once.
+func UseTOTPStep(id int, step int64) (bool, error) {
+	tag, err := database.Pool.Exec(
+		context.Background(),
+		`UPDATE admins SET totp_last_step=$1 WHERE id=$2 AND totp_last_step < $1`,
+		step, id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("error recording two-factor code: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// UseRecoveryCode marks the user's unused recovery code with codeHash as
+// used, reporting false if there is no such code.
+func UseRecoveryCode(id int, codeHash string) (bool, error) {
+	tag, err := database.Pool.Exec(
+		context.Background(),
+		`UPDATE admin_recovery_codes SET used_at=NOW()
+						WHERE admin_id=$1 AND code_hash=$2 AND used_at IS NULL`,
+		id, codeHash,
+	)
+	if err != nil {
+		return false, fmt.Errorf("error using recovery code: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has.
+func CountRecoveryCodes(id int) (int, error) {
+	var count int
+	err := database.Pool.QueryRow(
+		context.Background(),
+		`SELECT COUNT(*) FROM admin_recovery_codes WHERE admin_id=$1 AND used_at IS NULL`,
+		id,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting recovery codes: %w", err)
+	}
+	return count, nil
+}
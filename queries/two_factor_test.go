@@ -0,0 +1,56 @@
+package queries
+
+import (
+	"testing"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+func TestTwoFactor(t *testing.T) {
+	admin, err := CreateAdmin("two-factor-test@example.com", "hash", models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("Error creating admin: %v", err)
+	}
+	t.Cleanup(func() {
+		DeleteAdmin(admin.ID)
+	})
+
+	if err := EnableTwoFactor(admin.ID, "SECRET", 100, []string{"a", "b"}); err != nil {
+		t.Fatalf("Error enabling two-factor: %v", err)
+	}
+	admin, _ = GetAdminByID(admin.ID)
+	if !admin.TwoFactorEnabled() || admin.TOTPSecret != "SECRET" {
+		t.Fatalf("expected two-factor to be on, got %+v", admin)
+	}
+
+	if ok, _ := UseTOTPStep(admin.ID, 100); ok {
+		t.Error("expected the step used to enroll to be rejected")
+	}
+	if ok, _ := UseTOTPStep(admin.ID, 101); !ok {
+		t.Error("expected a later step to be accepted")
+	}
+	if ok, _ := UseTOTPStep(admin.ID, 101); ok {
+		t.Error("expected a replayed step to be rejected")
+	}
+
+	if ok, _ := UseRecoveryCode(admin.ID, "a"); !ok {
+		t.Error("expected an unused recovery code to work")
+	}
+	if ok, _ := UseRecoveryCode(admin.ID, "a"); ok {
+		t.Error("expected a recovery code to work only once")
+	}
+	if n, _ := CountRecoveryCodes(admin.ID); n != 1 {
+		t.Errorf("expected 1 unused code, got %d", n)
+	}
+
+	if err := DisableTwoFactor(admin.ID); err != nil {
+		t.Fatalf("Error disabling two-factor: %v", err)
+	}
+	admin, _ = GetAdminByID(admin.ID)
+	if admin.TwoFactorEnabled() || admin.TOTPSecret != "" {
+		t.Errorf("expected two-factor to be off, got %+v", admin)
+	}
+	if n, _ := CountRecoveryCodes(admin.ID); n != 0 {
+		t.Errorf("expected recovery codes to be deleted, got %d", n)
+	}
+}
@@ -0,0 +1,155 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrInvalidTransition is returned when a status change isn't allowed by the
+// workflow, or a post is scheduled without a future publish time.
+var ErrInvalidTransition = errors.New("status change not allowed")
+
+// TransitionPost moves a post to status to. The row is locked while the
+// current status is checked against models.CanTransition, so two editors
+// clicking at once can't skip a step. publishAt is required (and must be in
+// the future) when scheduling and ignored otherwise.
+func TransitionPost(id int, to models.PostStatus, publishAt *time.Time) error {
+	if to == models.StatusScheduled && (publishAt == nil || !publishAt.After(time.Now())) {
+		return ErrInvalidTransition
+	}
+
+	return withTx(func(tx pgx.Tx) error {
+		var from models.PostStatus
+		err := tx.QueryRow(
+			context.Background(),
+			`SELECT status FROM posts WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`,
+			id,
+		).Scan(&from)
+		if err != nil {
+			return fmt.Errorf("error fetching post: %w", err)
+		}
+		if !models.CanTransition(from, to) {
+			return ErrInvalidTransition
+		}
+
+		var at any
+		switch to {
+		case models.StatusScheduled:
+			at = *publishAt
+		case models.StatusPublished:
+			at = time.Now()
+		}
+		_, err = tx.Exec(
+			context.Background(),
+			`UPDATE posts SET status=$1, publish_at=$2, version=version+1, updated_at=NOW() WHERE id=$3`,
+			string(to), at, id,
+		)
+		if err != nil {
+			return fmt.Errorf("error updating post: %w", err)
+		}
+		return nil
+	})
+}
+
+// BulkTransition moves the given posts to status to in one transaction.
+// Posts the workflow doesn't allow to make that move, and trashed posts, are
+// left alone and not counted. Scheduling needs a per-post time, so it isn't
+// offered in bulk.
+func BulkTransition(ids []int, to models.PostStatus) (int64, error) {
+	if to == models.StatusScheduled {
+		return 0, ErrInvalidTransition
+	}
+	var from []string
+	for _, s := range models.StatusesAllowing(to) {
+		from = append(from, string(s))
+	}
+
+	var changed int64
+	err := withTx(func(tx pgx.Tx) error {
+		tag, err := tx.Exec(
+			context.Background(),
+			`UPDATE posts SET status=$1,
+					publish_at = CASE WHEN $1 = 'published' THEN NOW() ELSE NULL END,
+					version=version+1, updated_at=NOW()
+				WHERE id = ANY($2) AND status = ANY($3) AND deleted_at IS NULL`,
+			string(to), ids, from,
+		)
+		if err != nil {
+			return fmt.Errorf("error updating posts: %w", err)
+		}
+		changed = tag.RowsAffected()
+		return nil
+	})
+	return changed, err
+}
+
+// AssignReviewer asks an admin to review a post. A reviewerID of 0 clears
+// the assignment. It returns pgx.ErrNoRows if the post doesn't exist or is
+// in the trash.
+func AssignReviewer(postID, reviewerID int) error {
+	var reviewer any
+	if reviewerID != 0 {
+		reviewer = reviewerID
+	}
+	tag, err := database.Pool.Exec(
+		context.Background(),
+		`UPDATE posts SET reviewer_id=$1, updated_at=NOW() WHERE id=$2 AND deleted_at IS NULL`,
+		reviewer, postID,
+	)
+	if err != nil {
+		return fmt.Errorf("error assigning reviewer: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func AddReviewComment(postID, adminID int, body string) (models.ReviewComment, error) {
+	var c models.ReviewComment
+	err := database.Pool.QueryRow(
+		context.Background(),
+		`INSERT INTO post_review_comments (post_id, admin_id, body) VALUES ($1, $2, $3)
+			RETURNING id, post_id, admin_id, body, created_at`,
+		postID, adminID, body,
+	).Scan(&c.ID, &c.PostID, &c.AdminID, &c.Body, &c.CreatedAt)
+	if err != nil {
+		return models.ReviewComment{}, fmt.Errorf("error saving review comment: %w", err)
+	}
+	return c, nil
+}
+
+// GetReviewComments returns a post's review comments, oldest first. Comments
+// by since-deleted admins keep their body but lose the author.
+func GetReviewComments(postID int) ([]models.ReviewComment, error) {
+	rows, err := database.Pool.Query(
+		context.Background(),
+		`SELECT c.id, c.post_id, COALESCE(c.admin_id, 0), COALESCE(a.email, ''), c.body, c.created_at
+			FROM post_review_comments c
+			LEFT JOIN admins a ON a.id = c.admin_id
+			WHERE c.post_id=$1
+			ORDER BY c.created_at, c.id`,
+		postID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying review comments: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []models.ReviewComment
+	for rows.Next() {
+		var c models.ReviewComment
+		if err := rows.Scan(&c.ID, &c.PostID, &c.AdminID, &c.AuthorEmail, &c.Body, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error parsing review comment: %w", err)
+		}
+		comments = append(comments, c)
+	}
+
+	return comments, nil
+}
@@ -0,0 +1,125 @@
+package queries
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// publish walks a new draft through review to published.
+func publish(t *testing.T, id int) {
+	t.Helper()
+	for _, to := range []models.PostStatus{models.StatusInReview, models.StatusApproved, models.StatusPublished} {
+		if err := TransitionPost(id, to, nil); err != nil {
+			t.Fatalf("Error moving post to %s: %v", to, err)
+		}
+	}
+}
+
+func TestTransitionPost_EnforcesWorkflow(t *testing.T) {
+	post, err := CreatePost("Workflow Test", "tag", "body", "workflow-test", "", nil, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+
+	t.Cleanup(func() {
+		DeletePost(post.ID)
+	})
+
+	if post.Status != models.StatusDraft {
+		t.Errorf("expected new posts to be drafts, got %q", post.Status)
+	}
+	if err := TransitionPost(post.ID, models.StatusPublished, nil); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected a draft not to skip review, got: %v", err)
+	}
+
+	if err := TransitionPost(post.ID, models.StatusInReview, nil); err != nil {
+		t.Fatalf("Error submitting for review: %v", err)
+	}
+	if err := TransitionPost(post.ID, models.StatusApproved, nil); err != nil {
+		t.Fatalf("Error approving post: %v", err)
+	}
+	if err := TransitionPost(post.ID, models.StatusScheduled, nil); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected scheduling without a time to fail, got: %v", err)
+	}
+
+	at := time.Now().Add(time.Hour)
+	if err := TransitionPost(post.ID, models.StatusScheduled, &at); err != nil {
+		t.Fatalf("Error scheduling post: %v", err)
+	}
+
+	scheduled, err := GetPostByID(post.ID)
+	if err != nil {
+		t.Fatalf("Error fetching post: %v", err)
+	}
+	if scheduled.Status != models.StatusScheduled || scheduled.PublishAt == nil {
+		t.Errorf("expected a scheduled post with a publish time, got %q %v", scheduled.Status, scheduled.PublishAt)
+	}
+	if scheduled.Published() {
+		t.Error("expected a post scheduled in the future not to be public yet")
+	}
+}
+
+func TestListPosts_WaitingOn(t *testing.T) {
+	admin, err := CreateAdmin("reviewer-test@example.com", "hash", models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("Error creating admin: %v", err)
+	}
+	post, err := CreatePost("Review Me", "tag", "body", "review-me", "", nil, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+
+	t.Cleanup(func() {
+		DeletePost(post.ID)
+		DeleteAdmin(admin.ID)
+	})
+
+	if err := AssignReviewer(post.ID, admin.ID); err != nil {
+		t.Fatalf("Error assigning reviewer: %v", err)
+	}
+	filter := PostFilter{WaitingOn: admin.ID}
+	if count, _ := CountPosts(filter); count != 0 {
+		t.Errorf("expected drafts not to wait on the reviewer, got %d", count)
+	}
+
+	if err := TransitionPost(post.ID, models.StatusInReview, nil); err != nil {
+		t.Fatalf("Error submitting for review: %v", err)
+	}
+	posts, err := ListPosts(filter, 1, 10)
+	if err != nil {
+		t.Fatalf("Error listing posts: %v", err)
+	}
+	if len(posts) != 1 || posts[0].ID != post.ID {
+		t.Errorf("expected the post to be waiting on the reviewer, got %d posts", len(posts))
+	}
+
+	if _, err := AddReviewComment(post.ID, admin.ID, "Tighten the intro."); err != nil {
+		t.Fatalf("Error adding comment: %v", err)
+	}
+	comments, err := GetReviewComments(post.ID)
+	if err != nil {
+		t.Fatalf("Error fetching comments: %v", err)
+	}
+	if len(comments) != 1 || comments[0].AuthorEmail != admin.Email {
+		t.Errorf("expected one comment by %s, got %+v", admin.Email, comments)
+	}
+}
+
+func TestAssignReviewer_TrashedPost(t *testing.T) {
+	post, err := CreatePost("Trashed Review", "tag", "body", "trashed-review", "", nil, "", 0)
+	if err != nil {
+		t.Fatalf("Error creating post: %v", err)
+	}
+	t.Cleanup(func() { DeletePost(post.ID) })
+
+	if err := TrashPost(post.ID); err != nil {
+		t.Fatalf("Error trashing post: %v", err)
+	}
+	if err := AssignReviewer(post.ID, 0); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expected ErrNoRows for a trashed post, got %v", err)
+	}
+}
@@ -1,31 +1,52 @@
 //go:build ignore
 
+// Creates the first admin user, for a fresh database:
+//
+//	ADMIN_PASSWORD='a long password' go run scripts/seed_admin.go -email you@example.com
+//
+// Everyone else can then be added from /admin/users.
 package main
 
 import (
+			"flag"
 			"fmt"
 			"log"
+			"os"
 
-			"golang.org/x/crypto/bcrypt"
+			"github.com/joho/godotenv"
 
 			"github.com/hiimtaylorjones/hiimtaylor-go/database"
 			"github.com/hiimtaylorjones/hiimtaylor-go/models"
+			"github.com/hiimtaylorjones/hiimtaylor-go/passwords"
+			"github.com/hiimtaylorjones/hiimtaylor-go/queries"
+			"github.com/hiimtaylorjones/hiimtaylor-go/validation"
 )
 
 func main() {
+			email := flag.String("email", "", "email address to sign in with")
+			flag.Parse()
+
+			password := os.Getenv("ADMIN_PASSWORD")
+			if errs := validation.Account(*email, password, password, true); errs.Any() {
+							for field, msg := range errs {
+											log.Printf("%s: %s", field, msg)
+							}
+							log.Fatal("usage: ADMIN_PASSWORD=... go run scripts/seed_admin.go -email you@example.com")
+			}
+
+			godotenv.Load()
 			database.Connect()
 			defer database.Close()
 
-			password := "your-password-here"
-			hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+			hash, err := passwords.Hash(password)
 			if err != nil {
 							log.Fatal(err)
 			}
 
-			admin, err := models.CreateAdmin("admin@hiimtaylorjones.com", string(hash))
+			admin, err := queries.CreateAdmin(*email, hash, models.RoleAdmin)
 			if err != nil {
 							log.Fatal(err)
 			}
 
 			fmt.Printf("Created admin: %s (id: %d)\n", admin.Email, admin.ID)
-}
\ No newline at end of file
+}
@@ -0,0 +1,188 @@
+// Package sessionstore keeps scs sessions in Postgres, so sign-ins survive
+// restarts and are shared by every instance of the site.
+package sessionstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AdminIDKey is the session key holding the signed-in admin's ID as a
+// decimal string. Commit copies it into the admin_id column so one admin's
+// sessions can be found without reading everyone's.
+const AdminIDKey = "admin_id"
+
+// Store is an scs.Store and scs.IterableStore backed by the sessions table.
+// Session data is expected in scs's default gob encoding.
+type Store struct {
+	pool  *pgxpool.Pool
+	codec scs.Codec
+	stop  chan struct{}
+}
+
+// New returns a store using pool. If cleanupInterval is positive, expired
+// sessions, revoked ones included, are deleted in the background that often until StopCleanup is
+// called; expired sessions are never returned either way.
+func New(pool *pgxpool.Pool, cleanupInterval time.Duration) *Store {
+	s := &Store{pool: pool, codec: scs.GobCodec{}}
+	if cleanupInterval > 0 {
+		s.stop = make(chan struct{})
+		go s.cleanup(cleanupInterval)
+	}
+	return s
+}
+
+// Find returns the data for an unexpired session.
+func (s *Store) Find(token string) ([]byte, bool, error) {
+	var b []byte
+	err := s.pool.QueryRow(
+		context.Background(),
+		`SELECT data FROM sessions WHERE token = $1 AND NOT revoked AND expiry > NOW()`,
+		token,
+	).Scan(&b)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error finding session: %w", err)
+	}
+	return b, true, nil
+}
+
+// Commit saves a session, replacing its data and expiry if it exists, and
+// records which admin, if any, is signed in to it. A revoked session is
+// left revoked, so a request that was already in flight when it was
+// signed out can't save it back.
+func (s *Store) Commit(token string, b []byte, expiry time.Time) error {
+	_, err := s.pool.Exec(
+		context.Background(),
+		`INSERT INTO sessions (token, data, expiry, admin_id) VALUES ($1, $2, $3, $4)
+						ON CONFLICT (token) DO UPDATE
+						SET data = EXCLUDED.data, expiry = EXCLUDED.expiry, admin_id = EXCLUDED.admin_id
+						WHERE NOT sessions.revoked`,
+		token, b, expiry, s.adminID(b),
+	)
+	if err != nil {
+		return fmt.Errorf("error saving session: %w", err)
+	}
+	return nil
+}
+
+// Delete revokes a session. Its row is kept, without data, until it would
+// have expired so that the token can't be saved again. Deleting one that
+// doesn't exist is not an error.
+func (s *Store) Delete(token string) error {
+	_, err := s.pool.Exec(context.Background(), `UPDATE sessions SET `+revoke+` WHERE token = $1`, token)
+	if err != nil {
+		return fmt.Errorf("error deleting session: %w", err)
+	}
+	return nil
+}
+
+// revoke is the SET clause that turns a session into a tombstone.
+const revoke = `revoked = TRUE, data = '', admin_id = NULL`
+
+// adminID returns the admin signed in to the session encoded in b, or nil
+// when there is none. Data the codec can't read belongs to no one.
+func (s *Store) adminID(b []byte) *int {
+	_, values, err := s.codec.Decode(b)
+	if err != nil {
+		return nil
+	}
+	v, _ := values[AdminIDKey].(string)
+	id, err := strconv.Atoi(v)
+	if err != nil {
+		return nil
+	}
+	return &id
+}
+
+// All returns every unexpired session, keyed by token. It reads the whole
+// table, so it is for scs's Iterate and admin tooling; use ForAdmin to find
+// one admin's sessions.
+func (s *Store) All() (map[string][]byte, error) {
+	return s.query(`SELECT token, data FROM sessions WHERE NOT revoked AND expiry > NOW()`)
+}
+
+// ForAdmin returns the unexpired sessions adminID is signed in to, keyed by
+// token.
+func (s *Store) ForAdmin(adminID int) (map[string][]byte, error) {
+	return s.query(`SELECT token, data FROM sessions WHERE admin_id = $1 AND NOT revoked AND expiry > NOW()`, adminID)
+}
+
+// DeleteForAdmin revokes every session adminID is signed in to except the
+// one with token except, and reports how many there were.
+func (s *Store) DeleteForAdmin(adminID int, except string) (int64, error) {
+	tag, err := s.pool.Exec(
+		context.Background(),
+		`UPDATE sessions SET `+revoke+` WHERE admin_id = $1 AND token <> $2`,
+		adminID, except,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting sessions: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (s *Store) query(sql string, args ...any) (map[string][]byte, error) {
+	rows, err := s.pool.Query(context.Background(), sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make(map[string][]byte)
+	for rows.Next() {
+		var token string
+		var b []byte
+		if err := rows.Scan(&token, &b); err != nil {
+			return nil, fmt.Errorf("error parsing session: %w", err)
+		}
+		sessions[token] = b
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error querying sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// DeleteExpired removes expired sessions, and the tombstones of revoked
+// ones once they would have expired, and reports how many there were.
+func (s *Store) DeleteExpired() (int64, error) {
+	tag, err := s.pool.Exec(context.Background(), `DELETE FROM sessions WHERE expiry <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired sessions: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// StopCleanup stops the background cleanup started by New.
+func (s *Store) StopCleanup() {
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+func (s *Store) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.DeleteExpired(); err != nil {
+				log.Printf("session cleanup failed: %v", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
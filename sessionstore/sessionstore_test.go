@@ -0,0 +1,236 @@
+package sessionstore
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/joho/godotenv"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/database"
+)
+
+// The store must satisfy both scs interfaces.
+var (
+	_ scs.Store         = (*Store)(nil)
+	_ scs.IterableStore = (*Store)(nil)
+)
+
+func TestMain(m *testing.M) {
+	godotenv.Load()
+	if os.Getenv("DATABASE_URL") == "" {
+		log.Fatal("DATABASE_URL must be set to run tests")
+	}
+	database.Connect()
+	defer database.Close()
+	os.Exit(m.Run())
+}
+
+// newStore returns a store without background cleanup, and removes the
+// tokens a test used afterwards.
+func newStore(t *testing.T, tokens ...string) *Store {
+	t.Cleanup(func() {
+		database.Pool.Exec(context.Background(), `DELETE FROM sessions WHERE token = ANY($1)`, tokens)
+	})
+	return New(database.Pool, 0)
+}
+
+func TestStore_Contract(t *testing.T) {
+	s := newStore(t, "contract-token")
+
+	if _, found, err := s.Find("contract-token"); found || err != nil {
+		t.Fatalf("expected a missing session to be not found without error, got %v, %v", found, err)
+	}
+
+	if err := s.Commit("contract-token", []byte("one"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Error committing: %v", err)
+	}
+	b, found, err := s.Find("contract-token")
+	if err != nil || !found || !bytes.Equal(b, []byte("one")) {
+		t.Fatalf("expected to find the session, got %q, %v, %v", b, found, err)
+	}
+
+	if err := s.Commit("contract-token", []byte("two"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Error committing: %v", err)
+	}
+	if b, _, _ := s.Find("contract-token"); !bytes.Equal(b, []byte("two")) {
+		t.Errorf("expected a second commit to overwrite the data, got %q", b)
+	}
+
+	if err := s.Delete("contract-token"); err != nil {
+		t.Fatalf("Error deleting: %v", err)
+	}
+	if _, found, _ := s.Find("contract-token"); found {
+		t.Error("expected a deleted session to be gone")
+	}
+	if err := s.Delete("contract-token"); err != nil {
+		t.Errorf("expected deleting a missing session to be a no-op, got %v", err)
+	}
+}
+
+func TestStore_Expiry(t *testing.T) {
+	s := newStore(t, "expired-token", "live-token")
+
+	s.Commit("expired-token", []byte("old"), time.Now().Add(-time.Minute))
+	s.Commit("live-token", []byte("new"), time.Now().Add(time.Hour))
+
+	if _, found, _ := s.Find("expired-token"); found {
+		t.Error("expected an expired session to be not found")
+	}
+
+	all, err := s.All()
+	if err != nil {
+		t.Fatalf("Error listing sessions: %v", err)
+	}
+	if _, ok := all["expired-token"]; ok {
+		t.Error("expected All to leave out expired sessions")
+	}
+	if !bytes.Equal(all["live-token"], []byte("new")) {
+		t.Errorf("expected All to include the live session, got %q", all["live-token"])
+	}
+
+	if n, err := s.DeleteExpired(); err != nil || n < 1 {
+		t.Errorf("expected the expired session to be deleted, got %d, %v", n, err)
+	}
+	if _, found, _ := s.Find("live-token"); !found {
+		t.Error("expected cleanup to keep live sessions")
+	}
+}
+
+func TestStore_Cleanup(t *testing.T) {
+	newStore(t, "cleanup-token")
+	s := New(database.Pool, 10*time.Millisecond)
+	defer s.StopCleanup()
+
+	s.Commit("cleanup-token", []byte("old"), time.Now().Add(-time.Minute))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		var n int
+		database.Pool.QueryRow(context.Background(), `SELECT COUNT(*) FROM sessions WHERE token = 'cleanup-token'`).Scan(&n)
+		if n == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("expected the background cleanup to delete the expired row")
+}
+
+func TestStore_WithSessionManager(t *testing.T) {
+	sm := scs.New()
+	sm.Store = New(database.Pool, 0)
+
+	ctx, err := sm.Load(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Error loading session: %v", err)
+	}
+	sm.Put(ctx, "admin_id", "7")
+	token, _, err := sm.Commit(ctx)
+	if err != nil {
+		t.Fatalf("Error committing session: %v", err)
+	}
+	newStore(t, token)
+
+	// A second manager stands in for another instance or a restart.
+	other := scs.New()
+	other.Store = New(database.Pool, 0)
+	ctx, err = other.Load(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Error loading session: %v", err)
+	}
+	if got := other.GetString(ctx, "admin_id"); got != "7" {
+		t.Errorf("expected the session to be shared, got %q", got)
+	}
+}
+
+func TestStore_ForAdmin(t *testing.T) {
+	sm := scs.New()
+	store := New(database.Pool, 0)
+	sm.Store = store
+
+	// A high ID keeps the test clear of real admins' sessions.
+	const adminID = 987654
+	commit := func(values map[string]string) string {
+		t.Helper()
+		ctx, err := sm.Load(context.Background(), "")
+		if err != nil {
+			t.Fatalf("Error loading session: %v", err)
+		}
+		for k, v := range values {
+			sm.Put(ctx, k, v)
+		}
+		token, _, err := sm.Commit(ctx)
+		if err != nil {
+			t.Fatalf("Error committing session: %v", err)
+		}
+		return token
+	}
+	first := commit(map[string]string{AdminIDKey: "987654"})
+	second := commit(map[string]string{AdminIDKey: "987654"})
+	other := commit(map[string]string{AdminIDKey: "987655"})
+	anonymous := commit(map[string]string{"flash": "hello"})
+	newStore(t, first, second, other, anonymous)
+
+	sessions, err := store.ForAdmin(adminID)
+	if err != nil {
+		t.Fatalf("Error finding sessions: %v", err)
+	}
+	if len(sessions) != 2 || sessions[first] == nil || sessions[second] == nil {
+		t.Errorf("expected only the admin's two sessions, got %d", len(sessions))
+	}
+
+	// Signing out drops the admin from the session's row.
+	ctx, _ := sm.Load(context.Background(), second)
+	sm.Remove(ctx, AdminIDKey)
+	sm.Commit(ctx)
+	if sessions, _ := store.ForAdmin(adminID); len(sessions) != 1 {
+		t.Errorf("expected a signed-out session to be left out, got %d", len(sessions))
+	}
+
+	second = commit(map[string]string{AdminIDKey: "987654"})
+	newStore(t, second)
+	n, err := store.DeleteForAdmin(adminID, first)
+	if err != nil || n != 1 {
+		t.Fatalf("expected one session to be deleted, got %d, %v", n, err)
+	}
+	if _, found, _ := store.Find(first); !found {
+		t.Error("expected the excepted session to be kept")
+	}
+	if _, found, _ := store.Find(other); !found {
+		t.Error("expected another admin's session to be kept")
+	}
+}
+
+func TestStore_RevokedStaysRevoked(t *testing.T) {
+	s := newStore(t, "revoked-token", "revoked-admin-token")
+
+	s.Commit("revoked-token", []byte("one"), time.Now().Add(time.Hour))
+	if err := s.Delete("revoked-token"); err != nil {
+		t.Fatalf("Error deleting: %v", err)
+	}
+	// A request that loaded the session before it was deleted saves it on
+	// the way out.
+	if err := s.Commit("revoked-token", []byte("one"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Error committing: %v", err)
+	}
+	if _, found, _ := s.Find("revoked-token"); found {
+		t.Error("expected a deleted session to stay deleted after a late commit")
+	}
+
+	b, _ := scs.GobCodec{}.Encode(time.Now().Add(time.Hour), map[string]any{AdminIDKey: "987656"})
+	s.Commit("revoked-admin-token", b, time.Now().Add(time.Hour))
+	if _, err := s.DeleteForAdmin(987656, ""); err != nil {
+		t.Fatalf("Error deleting sessions: %v", err)
+	}
+	s.Commit("revoked-admin-token", b, time.Now().Add(time.Hour))
+	if _, found, _ := s.Find("revoked-admin-token"); found {
+		t.Error("expected a signed-out session to stay signed out after a late commit")
+	}
+	if sessions, _ := s.ForAdmin(987656); len(sessions) != 0 {
+		t.Errorf("expected no sessions left for the admin, got %d", len(sessions))
+	}
+}
@@ -1,6 +1,7 @@
 package slug
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 )
@@ -14,4 +15,20 @@ func Generate(title string) string {
 	s = regexp.MustCompile(`-+`).ReplaceAllString(s, "-")
 	s = strings.Trim(s, "-")
 	return s
-}
\ No newline at end of file
+}
+
+// Unique returns base if it is free, otherwise base-2, base-3, ... until
+// exists reports a free slug.
+func Unique(base string, exists func(string) (bool, error)) (string, error) {
+	candidate := base
+	for n := 2; ; n++ {
+		taken, err := exists(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
+}
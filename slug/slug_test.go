@@ -0,0 +1,38 @@
+package slug
+
+import "testing"
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{title: "Hello World", want: "hello-world"},
+		{title: "  Go & Postgres!  ", want: "go-postgres"},
+		{title: "Monthly Roundup -- March", want: "monthly-roundup-march"},
+	}
+
+	for _, tt := range tests {
+		if got := Generate(tt.title); got != tt.want {
+			t.Errorf("Generate(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestUnique(t *testing.T) {
+	taken := map[string]bool{"roundup-copy": true, "roundup-copy-2": true}
+	exists := func(s string) (bool, error) { return taken[s], nil }
+
+	got, err := Unique("roundup-copy", exists)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if got != "roundup-copy-3" {
+		t.Errorf("expected roundup-copy-3, got %q", got)
+	}
+
+	got, _ = Unique("fresh", exists)
+	if got != "fresh" {
+		t.Errorf("expected free base slug to be kept, got %q", got)
+	}
+}
@@ -0,0 +1,119 @@
+// Progressive enhancement for the post editor. Without JavaScript the form
+// is a plain textarea; with it, a preview pane renders the Markdown through
+// the same pipeline as the published post.
+
+// csrfToken reads the session's CSRF token from the layout's meta tag so
+// fetch requests pass the server's CSRF check.
+function csrfToken() {
+  var meta = document.querySelector('meta[name="csrf-token"]');
+  return meta ? meta.content : "";
+}
+
+(function () {
+  var body = document.getElementById("body");
+  var preview = document.getElementById("preview");
+  if (!body || !preview || !window.fetch) {
+    return;
+  }
+
+  var timer;
+  var inFlight = 0;
+
+  function render() {
+    var request = ++inFlight;
+    fetch("/admin/preview", {
+      method: "POST",
+      credentials: "same-origin",
+      headers: {
+        "Content-Type": "application/x-www-form-urlencoded",
+        "X-CSRF-Token": csrfToken()
+      },
+      body: new URLSearchParams({ body: body.value })
+    })
+      .then(function (res) {
+        if (!res.ok) {
+          throw new Error("preview failed: " + res.status);
+        }
+        return res.text();
+      })
+      .then(function (html) {
+        // Ignore responses that arrive after a newer request was sent.
+        if (request === inFlight) {
+          preview.innerHTML = html;
+          preview.classList.remove("preview-error");
+        }
+      })
+      .catch(function () {
+        preview.classList.add("preview-error");
+      });
+  }
+
+  body.addEventListener("input", function () {
+    clearTimeout(timer);
+    timer = setTimeout(render, 300);
+  });
+
+  preview.hidden = false;
+  body.closest(".editor").classList.add("editor-split");
+  render();
+})();
+
+// Autosave: every few seconds, if anything changed, post the editor state to
+// /admin/autosave. The server keeps it in post_drafts, separate from the
+// post, and offers it back if the editor is reopened before saving.
+(function () {
+  var form = document.querySelector("form[data-autosave]");
+  if (!form || !window.fetch) {
+    return;
+  }
+
+  var status = document.getElementById("autosave-status");
+  var postID = parseInt(form.getAttribute("data-post-id"), 10) || 0;
+  var fields = ["title", "tagline", "body"];
+
+  function snapshot() {
+    var state = { post_id: postID };
+    fields.forEach(function (name) {
+      var el = form.elements[name];
+      state[name] = el ? el.value : "";
+    });
+    return state;
+  }
+
+  var lastSaved = JSON.stringify(snapshot());
+  var submitting = false;
+
+  form.addEventListener("submit", function () {
+    submitting = true;
+  });
+
+  setInterval(function () {
+    var current = JSON.stringify(snapshot());
+    if (submitting || current === lastSaved) {
+      return;
+    }
+    fetch("/admin/autosave", {
+      method: "POST",
+      credentials: "same-origin",
+      headers: {
+        "Content-Type": "application/json",
+        "X-CSRF-Token": csrfToken()
+      },
+      body: current
+    })
+      .then(function (res) {
+        if (!res.ok) {
+          throw new Error("autosave failed: " + res.status);
+        }
+        lastSaved = current;
+        if (status) {
+          status.textContent = "Draft autosaved at " + new Date().toLocaleTimeString();
+        }
+      })
+      .catch(function () {
+        if (status) {
+          status.textContent = "Autosave failed; your changes are not saved yet.";
+        }
+      });
+  }, 5000);
+})();
@@ -0,0 +1,135 @@
+// Passkey sign-in on the login page and registration on the settings page.
+// Both are hidden until this script confirms the browser supports WebAuthn.
+// The server sends and expects binary fields as unpadded base64url.
+
+(function () {
+  if (!window.PublicKeyCredential || !window.fetch) {
+    return;
+  }
+
+  function csrfToken() {
+    var meta = document.querySelector('meta[name="csrf-token"]');
+    return meta ? meta.content : "";
+  }
+
+  function toBuffer(s) {
+    s = s.replace(/-/g, "+").replace(/_/g, "/");
+    while (s.length % 4) {
+      s += "=";
+    }
+    var bin = atob(s);
+    var bytes = new Uint8Array(bin.length);
+    for (var i = 0; i < bin.length; i++) {
+      bytes[i] = bin.charCodeAt(i);
+    }
+    return bytes.buffer;
+  }
+
+  function toBase64URL(buf) {
+    if (!buf) {
+      return "";
+    }
+    var bytes = new Uint8Array(buf);
+    var bin = "";
+    for (var i = 0; i < bytes.length; i++) {
+      bin += String.fromCharCode(bytes[i]);
+    }
+    return btoa(bin).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+  }
+
+  // post sends JSON and resolves with the JSON reply, or rejects with the
+  // server's plain-text error message.
+  function post(url, data) {
+    return fetch(url, {
+      method: "POST",
+      credentials: "same-origin",
+      headers: {
+        "Content-Type": "application/json",
+        "X-CSRF-Token": csrfToken()
+      },
+      body: JSON.stringify(data || {})
+    }).then(function (res) {
+      if (!res.ok) {
+        return res.text().then(function (text) {
+          throw new Error(text.trim() || "Something went wrong.");
+        });
+      }
+      return res.json();
+    });
+  }
+
+  function showError(el, err) {
+    // The browser rejects with NotAllowedError when the user cancels.
+    el.textContent = err.name === "NotAllowedError" ? "Passkey request was cancelled." : err.message;
+    el.hidden = false;
+  }
+
+  var login = document.getElementById("passkey-login");
+  if (login) {
+    var loginError = document.getElementById("passkey-login-error");
+    login.hidden = false;
+    login.querySelector("button").addEventListener("click", function () {
+      loginError.hidden = true;
+      post("/login/passkey/options")
+        .then(function (options) {
+          options.challenge = toBuffer(options.challenge);
+          return navigator.credentials.get({ publicKey: options });
+        })
+        .then(function (cred) {
+          return post("/login/passkey", {
+            rawId: toBase64URL(cred.rawId),
+            response: {
+              clientDataJSON: toBase64URL(cred.response.clientDataJSON),
+              authenticatorData: toBase64URL(cred.response.authenticatorData),
+              signature: toBase64URL(cred.response.signature),
+              userHandle: toBase64URL(cred.response.userHandle)
+            }
+          });
+        })
+        .then(function (reply) {
+          window.location = reply.redirect;
+        })
+        .catch(function (err) {
+          showError(loginError, err);
+        });
+    });
+  }
+
+  var register = document.getElementById("passkey-register");
+  if (register) {
+    var registerError = document.getElementById("passkey-register-error");
+    register.hidden = false;
+    document.getElementById("passkey-unsupported").hidden = true;
+    register.addEventListener("submit", function (e) {
+      e.preventDefault();
+      registerError.hidden = true;
+      post("/admin/settings/passkeys/options")
+        .then(function (options) {
+          options.challenge = toBuffer(options.challenge);
+          options.user.id = toBuffer(options.user.id);
+          options.excludeCredentials.forEach(function (c) {
+            c.id = toBuffer(c.id);
+          });
+          return navigator.credentials.create({ publicKey: options });
+        })
+        .then(function (cred) {
+          return post("/admin/settings/passkeys", {
+            name: document.getElementById("passkey_name").value,
+            credential: {
+              rawId: toBase64URL(cred.rawId),
+              response: {
+                clientDataJSON: toBase64URL(cred.response.clientDataJSON),
+                attestationObject: toBase64URL(cred.response.attestationObject)
+              }
+            }
+          });
+        })
+        .then(function (reply) {
+          window.location = reply.redirect;
+        })
+        .catch(function (err) {
+          showError(registerError, err);
+        });
+    });
+  }
+})();
@@ -0,0 +1,28 @@
+// Package tokens makes the secrets in emailed links, such as password
+// resets. Only a token's hash is stored, so a leaked database can't be used
+// to follow the links.
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// New returns a random URL-safe token and the hash to store for it.
+func New() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, Hash(token), nil
+}
+
+// Hash returns the stored form of token. Tokens are 256 random bits, so a
+// fast hash is as good as a slow one.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,21 @@
+package tokens
+
+import "testing"
+
+func TestNew(t *testing.T) {
+	token, hash, err := New()
+	if err != nil {
+		t.Fatalf("Error creating token: %v", err)
+	}
+	if len(token) != 43 || len(hash) != 64 {
+		t.Errorf("unexpected lengths: token %d, hash %d", len(token), len(hash))
+	}
+	if Hash(token) != hash {
+		t.Error("expected Hash to match the hash New returned")
+	}
+
+	other, _, _ := New()
+	if other == token {
+		t.Error("expected tokens to differ")
+	}
+}
@@ -0,0 +1,110 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The SHA1 test vectors from RFC 6238, appendix B.
+func TestHOTP_RFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		got := hotp(key, uint64(Step(time.Unix(tt.unix, 0))), 8)
+		if got != tt.want {
+			t.Errorf("at %d: expected %s, got %s", tt.unix, tt.want, got)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111109, 0)
+
+	code, err := Code(secret, now)
+	if err != nil {
+		t.Fatalf("Error generating code: %v", err)
+	}
+	if code != "081804" {
+		t.Errorf("expected the last six digits of the RFC vector, got %s", code)
+	}
+
+	step, ok := Verify(secret, code, now)
+	if !ok || step != Step(now) {
+		t.Errorf("expected the current code to verify at step %d, got %d, %v", Step(now), step, ok)
+	}
+	if _, ok := Verify(secret, code, now.Add(Period)); !ok {
+		t.Error("expected the previous step's code to be accepted")
+	}
+	if _, ok := Verify(secret, code, now.Add(3*Period)); ok {
+		t.Error("expected a code from three steps ago to be rejected")
+	}
+	if _, ok := Verify(secret, "000000", now); ok {
+		t.Error("expected a wrong code to be rejected")
+	}
+	if _, ok := Verify(secret, "08180", now); ok {
+		t.Error("expected a short code to be rejected")
+	}
+}
+
+func TestGenerateSecretAndURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("Error generating secret: %v", err)
+	}
+	if _, err := Code(secret, time.Now()); err != nil {
+		t.Errorf("expected a usable secret, got %v", err)
+	}
+
+	u, err := url.Parse(URI("hiimtaylor", "me@example.com", secret))
+	if err != nil {
+		t.Fatalf("Error parsing URI: %v", err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/hiimtaylor:me@example.com" {
+		t.Errorf("unexpected URI %s", u)
+	}
+	if u.Query().Get("secret") != secret || u.Query().Get("issuer") != "hiimtaylor" {
+		t.Errorf("expected the secret and issuer in the query, got %s", u.RawQuery)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := RecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		t.Fatalf("Error generating codes: %v", err)
+	}
+	if len(codes) != RecoveryCodeCount {
+		t.Fatalf("expected %d codes, got %d", RecoveryCodeCount, len(codes))
+	}
+
+	seen := map[string]bool{}
+	for _, c := range codes {
+		if len(c) != 11 || c[5] != '-' {
+			t.Errorf("unexpected code format %q", c)
+		}
+		if seen[c] {
+			t.Errorf("duplicate code %q", c)
+		}
+		seen[c] = true
+	}
+
+	typed := strings.ToUpper(strings.Replace(codes[0], "-", " ", 1))
+	if HashRecoveryCode(typed) != HashRecoveryCode(codes[0]) {
+		t.Error("expected case and separators to be ignored when hashing")
+	}
+}
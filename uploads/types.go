@@ -0,0 +1,84 @@
+package uploads
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrFileType is returned for a file whose extension or contents aren't an
+// accepted type.
+var ErrFileType = errors.New("file type not allowed")
+
+// fileType is an accepted kind of file: the MIME type it is recorded with,
+// and what http.DetectContentType reports for the start of such a file.
+type fileType struct {
+	contentType string
+	sniffed     []string
+}
+
+// imageTypes are the images shown inline on the site.
+var imageTypes = map[string]fileType{
+	".jpg":  {"image/jpeg", []string{"image/jpeg"}},
+	".jpeg": {"image/jpeg", []string{"image/jpeg"}},
+	".png":  {"image/png", []string{"image/png"}},
+	".gif":  {"image/gif", []string{"image/gif"}},
+	".webp": {"image/webp", []string{"image/webp"}},
+}
+
+// attachmentTypes are the files posts can offer for download: PDFs, slide
+// decks and audio. Formats the sniffer has no signature for are allowed to
+// sniff as application/octet-stream, and zip-based decks as application/zip;
+// what matters is that nothing sniffs as HTML, XML or script.
+var attachmentTypes = map[string]fileType{
+	".pdf":  {"application/pdf", []string{"application/pdf"}},
+	".mp3":  {"audio/mpeg", []string{"audio/mpeg", "application/octet-stream"}},
+	".m4a":  {"audio/mp4", []string{"video/mp4", "application/octet-stream"}},
+	".ogg":  {"audio/ogg", []string{"application/ogg"}},
+	".wav":  {"audio/wav", []string{"audio/wave"}},
+	".ppt":  {"application/vnd.ms-powerpoint", []string{"application/octet-stream"}},
+	".pptx": {"application/vnd.openxmlformats-officedocument.presentationml.presentation", []string{"application/zip"}},
+	".odp":  {"application/vnd.oasis.opendocument.presentation", []string{"application/zip"}},
+	".key":  {"application/vnd.apple.keynote", []string{"application/zip"}},
+}
+
+// identify checks a file named name against types, first by extension and
+// then by sniffing its first bytes, and leaves file positioned at its
+// start. It returns the lowercased extension to store the file under.
+func identify(file multipart.File, name string, types map[string]fileType) (string, fileType, error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	t, ok := types[ext]
+	if !ok {
+		return "", fileType{}, ErrFileType
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", fileType{}, fmt.Errorf("could not read upload: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", fileType{}, fmt.Errorf("could not read upload: %w", err)
+	}
+	if !slices.Contains(t.sniffed, http.DetectContentType(head[:n])) {
+		return "", fileType{}, ErrFileType
+	}
+	return ext, t, nil
+}
+
+// check opens header and identifies it against types.
+func check(header *multipart.FileHeader, types map[string]fileType) error {
+	file, err := header.Open()
+	if err != nil {
+		return fmt.Errorf("could not open upload: %w", err)
+	}
+	defer file.Close()
+
+	_, _, err = identify(file, header.Filename, types)
+	return err
+}
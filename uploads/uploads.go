@@ -1,36 +1,141 @@
 package uploads
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"os"
 	"path/filepath"
-	"time"
+	"strings"
 )
 
-const uploadDir = "static/uploads"
+// uploadDir is where files are stored. It is a variable so tests can point
+// it at a temporary directory.
+var uploadDir = "static/uploads"
 
+// Save stores an uploaded image and returns its URL. Only JPEG, PNG, GIF and
+// WebP images are accepted, checked by both extension and contents;
+// anything else is ErrFileType.
 func Save(file multipart.File, header *multipart.FileHeader) (string, error) {
+	ext, _, err := identify(file, header.Filename, imageTypes)
+	if err != nil {
+		return "", err
+	}
+	return store(file, ext)
+}
+
+// store writes file to the upload directory under a new name ending in ext
+// and returns its URL.
+func store(file io.Reader, ext string) (string, error) {
 	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
 		return "", fmt.Errorf("could not create upload directory: %w", err)
 	}
 
-	ext := filepath.Ext(header.Filename)
-	// Utilizes time.Now... call to specify a unique upload timestamp
-	// this timestamp is used to ensure the uniqueness of filename. 
-	filename := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
+	// Names are random rather than timestamps, so uploads saved in the same
+	// clock tick can't collide, and O_EXCL makes sure an existing file is
+	// never overwritten even if they somehow do.
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", fmt.Errorf("could not name file: %w", err)
+	}
+	filename := hex.EncodeToString(name) + ext
 	dest := filepath.Join(uploadDir, filename)
 
-	out, err := os.Create(dest)
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
 	if err != nil {
 		return "", fmt.Errorf("could not create file: %w", err)
 	}
 	defer out.Close()
 
 	if _, err := io.Copy(out, file); err != nil {
+		os.Remove(dest)
 		return "", fmt.Errorf("could not save file: %w", err)
 	}
 
 	return "/static/uploads/" + filename, nil
-}
\ No newline at end of file
+}
+
+// Remove deletes the stored upload at url, as returned by Save. It is for
+// undoing a save when whatever the file was for didn't go through, and for
+// cleaning up after posts that are deleted for good.
+func Remove(url string) error {
+	name := strings.TrimPrefix(url, "/static/uploads/")
+	if name == url || name != filepath.Base(name) {
+		return fmt.Errorf("not an upload: %q", url)
+	}
+	if err := os.Remove(filepath.Join(uploadDir, name)); err != nil {
+		return fmt.Errorf("could not remove file: %w", err)
+	}
+	return nil
+}
+
+// SaveHeader opens a file from a parsed multipart form and stores it with
+// Save. It is used for multi-file inputs, where there is no single
+// r.FormFile to read from.
+func SaveHeader(header *multipart.FileHeader) (string, error) {
+	file, err := header.Open()
+	if err != nil {
+		return "", fmt.Errorf("could not open upload: %w", err)
+	}
+	defer file.Close()
+
+	return Save(file, header)
+}
+
+// Info describes a stored upload for callers that need more than its URL,
+// such as post attachments and feed enclosures.
+type Info struct {
+	URL         string
+	Filename    string
+	ContentType string
+	Size        int64
+}
+
+// SaveWithInfo stores an uploaded attachment and reports its original
+// filename, size and MIME type. Only PDFs, slide decks and audio files are
+// accepted (ErrFileType otherwise), and the MIME type comes from that list,
+// never from the browser.
+func SaveWithInfo(header *multipart.FileHeader) (Info, error) {
+	file, err := header.Open()
+	if err != nil {
+		return Info{}, fmt.Errorf("could not open upload: %w", err)
+	}
+	defer file.Close()
+
+	ext, t, err := identify(file, header.Filename, attachmentTypes)
+	if err != nil {
+		return Info{}, err
+	}
+	url, err := store(file, ext)
+	if err != nil {
+		return Info{}, err
+	}
+
+	return Info{
+		URL:         url,
+		Filename:    filepath.Base(header.Filename),
+		ContentType: t.contentType,
+		Size:        header.Size,
+	}, nil
+}
+
+// CheckImage reports ErrFileType unless header holds an image Save accepts,
+// so a form can be rejected before anything is saved.
+func CheckImage(header *multipart.FileHeader) error {
+	return check(header, imageTypes)
+}
+
+// CheckAttachment reports ErrFileType unless header holds a file
+// SaveWithInfo accepts, so a form can be rejected before anything is saved.
+func CheckAttachment(header *multipart.FileHeader) error {
+	return check(header, attachmentTypes)
+}
+
+// Inline reports whether the upload at path is an image, which is safe to
+// show in the page. Anything else is served as a download.
+func Inline(path string) bool {
+	_, ok := imageTypes[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
@@ -0,0 +1,171 @@
+package uploads
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// png is the start of a PNG file, enough for it to be recognised as one.
+var png = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// useTempDir stores uploads in a temporary directory for the test.
+func useTempDir(t *testing.T) {
+	old := uploadDir
+	uploadDir = t.TempDir()
+	t.Cleanup(func() { uploadDir = old })
+}
+
+// fileHeader builds a multipart file header for name holding content, as a
+// parsed form would have it.
+func fileHeader(t *testing.T, name string, content []byte) *multipart.FileHeader {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	part, err := w.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	w.Close()
+
+	req := httptest.NewRequest("POST", "/", &buf)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	return req.MultipartForm.File["file"][0]
+}
+
+func TestSaveHeader_NamesDoNotCollide(t *testing.T) {
+	useTempDir(t)
+
+	seen := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		content := append(bytes.Clone(png), strings.Repeat("x", i+1)...)
+		url, err := SaveHeader(fileHeader(t, "photo.png", content))
+		if err != nil {
+			t.Fatalf("SaveHeader: %v", err)
+		}
+		if seen[url] {
+			t.Fatalf("upload %d reused the name %s", i, url)
+		}
+		seen[url] = true
+
+		stored, err := os.ReadFile(filepath.Join(uploadDir, filepath.Base(url)))
+		if err != nil || !bytes.Equal(stored, content) {
+			t.Errorf("expected %s to hold upload %d, got %q (err %v)", url, i, stored, err)
+		}
+	}
+}
+
+func TestSaveHeader_OnlyAcceptsImages(t *testing.T) {
+	useTempDir(t)
+
+	url, err := SaveHeader(fileHeader(t, "Photo.PNG", png))
+	if err != nil {
+		t.Fatalf("expected a PNG to be accepted, got %v", err)
+	}
+	if !strings.HasSuffix(url, ".png") {
+		t.Errorf("expected the stored name to end in .png, got %s", url)
+	}
+
+	rejected := []struct{ name, content string }{
+		{"page.html", "<html><script>alert(1)</script></html>"},
+		{"logo.svg", `<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`},
+		{"fake.png", "<html><script>alert(1)</script></html>"},
+		{"slides.pdf", "%PDF-1.7"},
+		{"photo", string(png)},
+	}
+	for _, f := range rejected {
+		if _, err := SaveHeader(fileHeader(t, f.name, []byte(f.content))); !errors.Is(err, ErrFileType) {
+			t.Errorf("expected %s to be rejected with ErrFileType, got %v", f.name, err)
+		}
+		if err := CheckImage(fileHeader(t, f.name, []byte(f.content))); !errors.Is(err, ErrFileType) {
+			t.Errorf("expected CheckImage to reject %s, got %v", f.name, err)
+		}
+	}
+
+	entries, _ := os.ReadDir(uploadDir)
+	if len(entries) != 1 {
+		t.Errorf("expected only the accepted image to be stored, found %d", len(entries))
+	}
+}
+
+func TestSaveWithInfo_OnlyAcceptsAttachmentTypes(t *testing.T) {
+	useTempDir(t)
+
+	info, err := SaveWithInfo(fileHeader(t, "Slides.PDF", []byte("%PDF-1.7\n...")))
+	if err != nil {
+		t.Fatalf("expected a PDF to be accepted, got %v", err)
+	}
+	if info.ContentType != "application/pdf" || info.Filename != "Slides.PDF" || !strings.HasSuffix(info.URL, ".pdf") {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if _, err := SaveWithInfo(fileHeader(t, "episode.mp3", []byte("ID3\x04\x00audio"))); err != nil {
+		t.Errorf("expected an MP3 to be accepted, got %v", err)
+	}
+
+	rejected := []struct{ name, content string }{
+		{"page.html", "<html><script>alert(1)</script></html>"},
+		{"logo.svg", `<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`},
+		{"notes.txt", "plain text"},
+		{"fake.pdf", "<html><script>alert(1)</script></html>"},
+		{"fake.mp3", "<svg onload=alert(1)>"},
+		{"noextension", "%PDF-1.7"},
+	}
+	for _, f := range rejected {
+		if _, err := SaveWithInfo(fileHeader(t, f.name, []byte(f.content))); !errors.Is(err, ErrFileType) {
+			t.Errorf("expected %s to be rejected with ErrFileType, got %v", f.name, err)
+		}
+		if err := CheckAttachment(fileHeader(t, f.name, []byte(f.content))); !errors.Is(err, ErrFileType) {
+			t.Errorf("expected CheckAttachment to reject %s, got %v", f.name, err)
+		}
+	}
+
+	entries, _ := os.ReadDir(uploadDir)
+	if len(entries) != 2 {
+		t.Errorf("expected only the accepted files to be stored, found %d", len(entries))
+	}
+}
+
+func TestInline(t *testing.T) {
+	for path, want := range map[string]bool{
+		"uploads/a.png":  true,
+		"uploads/a.JPG":  true,
+		"uploads/a.svg":  false,
+		"uploads/a.html": false,
+		"uploads/a.pdf":  false,
+		"uploads/a":      false,
+	} {
+		if got := Inline(path); got != want {
+			t.Errorf("Inline(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	useTempDir(t)
+
+	url, err := SaveHeader(fileHeader(t, "banner.png", png))
+	if err != nil {
+		t.Fatalf("SaveHeader: %v", err)
+	}
+	if err := Remove(url); err != nil {
+		t.Fatalf("Remove(%q): %v", url, err)
+	}
+	if _, err := os.Stat(filepath.Join(uploadDir, filepath.Base(url))); !os.IsNotExist(err) {
+		t.Errorf("file still there after Remove: %v", err)
+	}
+
+	for _, url := range []string{"/static/css/site.css", "/static/uploads/../css/site.css"} {
+		if err := Remove(url); err == nil {
+			t.Errorf("Remove(%q) = nil, want an error", url)
+		}
+	}
+}
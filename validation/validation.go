@@ -0,0 +1,124 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+	"github.com/hiimtaylorjones/hiimtaylor-go/passwords"
+	"github.com/hiimtaylorjones/hiimtaylor-go/slug"
+)
+
+// Errors maps a form field name to what is wrong with it. A nil or empty
+// Errors means the input is valid.
+type Errors map[string]string
+
+func (e Errors) Any() bool {
+	return len(e) > 0
+}
+
+// Get returns the message for field, or "". Templates use it as
+// {{.Errors.Get "title"}}.
+func (e Errors) Get(field string) string {
+	return e[field]
+}
+
+const maxTitleLength = 255
+
+// Post checks the fields of the post form.
+func Post(p models.Post) Errors {
+	errs := Errors{}
+
+	title := strings.TrimSpace(p.Title)
+	switch {
+	case title == "":
+		errs["title"] = "Title is required."
+	case slug.Generate(title) == "":
+		errs["title"] = "Title needs at least one letter or number so the post gets a URL."
+	case utf8.RuneCountInString(title) > maxTitleLength:
+		errs["title"] = "Title must be 255 characters or fewer."
+	}
+
+	if strings.TrimSpace(p.Body) == "" {
+		errs["body"] = "Body is required."
+	}
+
+	if u := p.Meta("canonical_url"); u != "" &&
+		!strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://") {
+		errs["meta"] = "canonical_url must be an absolute http(s) URL."
+	}
+
+	return errs
+}
+
+const maxBioLength = 2000
+
+// Profile checks the public author profile form. A profile needs a name
+// before it gets an author page, and the page's slug must be usable in a
+// URL.
+func Profile(a models.Admin) Errors {
+	errs := Errors{}
+
+	name := strings.TrimSpace(a.Name)
+	switch {
+	case utf8.RuneCountInString(name) > maxTitleLength:
+		errs["name"] = "Name must be 255 characters or fewer."
+	case name == "" && a.Slug != "":
+		errs["name"] = "Add a name before choosing a page address."
+	}
+
+	if a.Slug != "" && slug.Generate(a.Slug) != a.Slug {
+		errs["slug"] = "Use lowercase letters, numbers and dashes only."
+	}
+
+	if utf8.RuneCountInString(a.Bio) > maxBioLength {
+		errs["bio"] = "Bio must be 2000 characters or fewer."
+	}
+
+	return errs
+}
+
+// Account checks the email and new password fields shared by the settings
+// and user management forms. Empty fields are skipped so a form can check
+// just one of them; pass required to insist on both.
+func Account(email, password, confirmation string, required bool) Errors {
+	errs := Errors{}
+
+	email = strings.TrimSpace(email)
+	switch {
+	case email == "" && required:
+		errs["email"] = "Email is required."
+	case email != "":
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+			errs["email"] = "Enter an email address like name@example.com."
+		}
+	}
+
+	switch err := passwords.Check(password); {
+	case password == "" && required:
+		errs["password"] = "Password is required."
+	case password == "":
+	case err != nil:
+		errs["password"] = passwordMessage(err)
+	case password != confirmation:
+		errs["password_confirmation"] = "Passwords don't match."
+	}
+
+	return errs
+}
+
+// passwordMessage explains why passwords.Check rejected a password.
+func passwordMessage(err error) string {
+	switch {
+	case errors.Is(err, passwords.ErrTooShort):
+		return fmt.Sprintf("Password must be at least %d characters.", passwords.MinLength)
+	case errors.Is(err, passwords.ErrTooLong):
+		return fmt.Sprintf("Password must be %d characters or fewer.", passwords.MaxLength)
+	case errors.Is(err, passwords.ErrCommon):
+		return "That password is too common. Choose one that's harder to guess."
+	}
+	return "Choose a different password."
+}
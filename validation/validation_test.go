@@ -0,0 +1,133 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hiimtaylorjones/hiimtaylor-go/models"
+)
+
+func TestPost(t *testing.T) {
+	tests := []struct {
+		name   string
+		post   models.Post
+		fields []string
+	}{
+		{
+			name: "valid",
+			post: models.Post{Title: "Hello", Body: "World"},
+		},
+		{
+			name:   "empty title and body",
+			post:   models.Post{Title: "   ", Body: ""},
+			fields: []string{"title", "body"},
+		},
+		{
+			name:   "all-symbol title",
+			post:   models.Post{Title: "!!! ???", Body: "World"},
+			fields: []string{"title"},
+		},
+		{
+			name:   "overlong title",
+			post:   models.Post{Title: strings.Repeat("a", 256), Body: "World"},
+			fields: []string{"title"},
+		},
+		{
+			name:   "relative canonical url",
+			post:   models.Post{Title: "Hello", Body: "World", Metadata: models.PostMeta{"canonical_url": "/posts/x"}},
+			fields: []string{"meta"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Post(tt.post)
+			if len(errs) != len(tt.fields) {
+				t.Fatalf("expected errors on %v, got %v", tt.fields, errs)
+			}
+			for _, f := range tt.fields {
+				if errs.Get(f) == "" {
+					t.Errorf("expected an error on %q, got %v", f, errs)
+				}
+			}
+		})
+	}
+}
+
+func TestProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile models.Admin
+		fields  []string
+	}{
+		{
+			name:    "valid",
+			profile: models.Admin{Name: "Taylor Jones", Slug: "taylor-jones", Bio: "Writes things."},
+		},
+		{
+			name: "empty profile",
+		},
+		{
+			name:    "slug without a name",
+			profile: models.Admin{Slug: "taylor"},
+			fields:  []string{"name"},
+		},
+		{
+			name:    "slug with spaces and capitals",
+			profile: models.Admin{Name: "Taylor", Slug: "Taylor Jones"},
+			fields:  []string{"slug"},
+		},
+		{
+			name:    "overlong bio",
+			profile: models.Admin{Name: "Taylor", Bio: strings.Repeat("a", 2001)},
+			fields:  []string{"bio"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Profile(tt.profile)
+			if len(errs) != len(tt.fields) {
+				t.Fatalf("expected errors on %v, got %v", tt.fields, errs)
+			}
+			for _, f := range tt.fields {
+				if errs.Get(f) == "" {
+					t.Errorf("expected an error on %q, got %v", f, errs)
+				}
+			}
+		})
+	}
+}
+
+func TestAccount(t *testing.T) {
+	tests := []struct {
+		name                          string
+		email, password, confirmation string
+		required                      bool
+		fields                        []string
+	}{
+		{name: "valid", email: "a@example.com", password: "long enough pw", confirmation: "long enough pw", required: true},
+		{name: "missing both", required: true, fields: []string{"email", "password"}},
+		{name: "optional fields left blank"},
+		{name: "bad email", email: "not an email", fields: []string{"email"}},
+		{name: "display name in email", email: "Taylor <a@example.com>", fields: []string{"email"}},
+		{name: "short password", password: "short", confirmation: "short", fields: []string{"password"}},
+		{name: "common password", password: "password1234", confirmation: "password1234", fields: []string{"password"}},
+		{name: "long password", password: strings.Repeat("a", 257), confirmation: strings.Repeat("a", 257), fields: []string{"password"}},
+		{name: "mismatch", password: "long enough pw", confirmation: "long enough pw!", fields: []string{"password_confirmation"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Account(tt.email, tt.password, tt.confirmation, tt.required)
+			if len(errs) != len(tt.fields) {
+				t.Fatalf("expected errors on %v, got %v", tt.fields, errs)
+			}
+			for _, f := range tt.fields {
+				if errs.Get(f) == "" {
+					t.Errorf("expected an error on %q, got %v", f, errs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+// pair is one map entry for encodeCBOR, which keeps entries in the order
+// given so tests produce the bytes an authenticator would.
+type pair struct {
+	k, v any
+}
+
+// encodeCBOR encodes the subset of CBOR the tests need.
+func encodeCBOR(v any) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n <= 0xff:
+			return []byte{major<<5 | 24, byte(n)}
+		case n <= 0xffff:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+		case n <= 0xffffffff:
+			return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+		}
+		return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, n)
+	}
+
+	switch v := v.(type) {
+	case int:
+		if v < 0 {
+			return head(1, uint64(-1-v))
+		}
+		return head(0, uint64(v))
+	case []byte:
+		return append(head(2, uint64(len(v))), v...)
+	case string:
+		return append(head(3, uint64(len(v))), v...)
+	case bool:
+		if v {
+			return []byte{0xf5}
+		}
+		return []byte{0xf4}
+	case []any:
+		b := head(4, uint64(len(v)))
+		for _, item := range v {
+			b = append(b, encodeCBOR(item)...)
+		}
+		return b
+	case []pair:
+		b := head(5, uint64(len(v)))
+		for _, p := range v {
+			b = append(b, encodeCBOR(p.k)...)
+			b = append(b, encodeCBOR(p.v)...)
+		}
+		return b
+	}
+	panic("encodeCBOR: unsupported type")
+}
+
+// authenticator is a software passkey for one credential, standing in for
+// the browser and security key in tests.
+type authenticator struct {
+	t         *testing.T
+	rpID      string
+	origin    string
+	id        []byte
+	key       crypto.Signer
+	signCount uint32
+	flags     byte
+}
+
+func newAuthenticator(t *testing.T, rp RelyingParty, alg int) *authenticator {
+	a := &authenticator{
+		t:      t,
+		rpID:   rp.ID,
+		origin: rp.Origin,
+		id:     []byte("credential-" + t.Name()),
+		flags:  flagUserPresent | flagUserVerified,
+	}
+	var err error
+	switch alg {
+	case AlgES256:
+		a.key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgRS256:
+		a.key, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	return a
+}
+
+func (a *authenticator) coseKey() []byte {
+	switch pub := a.key.Public().(type) {
+	case *ecdsa.PublicKey:
+		return encodeCBOR([]pair{
+			{coseKty, coseKtyEC2},
+			{coseAlg, AlgES256},
+			{coseEC2Crv, coseCrvP256},
+			{coseEC2X, pub.X.FillBytes(make([]byte, 32))},
+			{coseEC2Y, pub.Y.FillBytes(make([]byte, 32))},
+		})
+	case *rsa.PublicKey:
+		return encodeCBOR([]pair{
+			{coseKty, coseKtyRSA},
+			{coseAlg, AlgRS256},
+			{coseRSAN, pub.N.Bytes()},
+			{coseRSAE, big.NewInt(int64(pub.E)).Bytes()},
+		})
+	}
+	return nil
+}
+
+func (a *authenticator) clientData(typ string, challenge []byte) []byte {
+	b, _ := json.Marshal(map[string]any{
+		"type":        typ,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      a.origin,
+		"crossOrigin": false,
+	})
+	return b
+}
+
+func (a *authenticator) authData(attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	b := append([]byte(nil), rpIDHash[:]...)
+	flags := a.flags
+	if attested {
+		flags |= flagAttestedCredData
+	}
+	b = append(b, flags)
+	b = binary.BigEndian.AppendUint32(b, a.signCount)
+	if attested {
+		b = append(b, make([]byte, 16)...) // AAGUID
+		b = binary.BigEndian.AppendUint16(b, uint16(len(a.id)))
+		b = append(b, a.id...)
+		b = append(b, a.coseKey()...)
+	}
+	return b
+}
+
+// create answers navigator.credentials.create.
+func (a *authenticator) create(challenge []byte) RegistrationResponse {
+	var resp RegistrationResponse
+	resp.ID = a.id
+	resp.Response.ClientDataJSON = a.clientData("webauthn.create", challenge)
+	resp.Response.AttestationObject = encodeCBOR([]pair{
+		{"fmt", "none"},
+		{"attStmt", []pair{}},
+		{"authData", a.authData(true)},
+	})
+	return resp
+}
+
+// get answers navigator.credentials.get, bumping the signature counter.
+func (a *authenticator) get(challenge []byte) AssertionResponse {
+	a.signCount++
+	var resp AssertionResponse
+	resp.ID = a.id
+	resp.Response.ClientDataJSON = a.clientData("webauthn.get", challenge)
+	resp.Response.AuthenticatorData = a.authData(false)
+	resp.Response.UserHandle = []byte("42")
+
+	hash := sha256.Sum256(resp.Response.ClientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), resp.Response.AuthenticatorData...), hash[:]...))
+	sig, err := a.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		a.t.Fatalf("Error signing: %v", err)
+	}
+	resp.Response.Signature = sig
+	return resp
+}
@@ -0,0 +1,63 @@
+package webauthn
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	data := encodeCBOR([]pair{
+		{1, 2},
+		{-1, -300},
+		{"name", "passkey"},
+		{"bytes", []byte{0xde, 0xad}},
+		{"list", []any{true, false, 70000}},
+	})
+	data = append(data, 0x01)
+
+	v, rest, err := decodeCBOR(data)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := map[any]any{
+		int64(1):  int64(2),
+		int64(-1): int64(-300),
+		"name":    "passkey",
+		"bytes":   []byte{0xde, 0xad},
+		"list":    []any{true, false, int64(70000)},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("expected %#v, got %#v", want, v)
+	}
+	if len(rest) != 1 || rest[0] != 0x01 {
+		t.Errorf("expected the trailing byte to be returned, got %x", rest)
+	}
+}
+
+func TestDecodeCBOR_Rejects(t *testing.T) {
+	nested := make([]byte, 100)
+	for i := range nested {
+		nested[i] = 0x81 // array of one item
+	}
+
+	tests := map[string][]byte{
+		"empty":             {},
+		"truncated string":  {0x45, 0x01, 0x02},
+		"truncated length":  {0x59, 0x01},
+		"indefinite length": {0x5f},
+		"huge array":        {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"float":             {0xf9, 0x3c, 0x00},
+		"tag":               {0xc0, 0x00},
+		"array map key":     {0xa1, 0x80, 0x00},
+		"too deeply nested": nested,
+		"integer overflow":  {0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := decodeCBOR(data); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+// Package webauthn implements the server side of passkey registration and
+// sign-in (Web Authentication, Level 2) for the cases this site needs:
+// ES256 and RS256 keys, "none" attestation, and user verification required,
+// so a passkey stands in for both the password and the second factor.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Timeout is how long, in milliseconds, the browser should wait for the
+// user to respond to a ceremony.
+const Timeout = 120000
+
+// Authenticator data flags.
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagAttestedCredData = 0x40
+)
+
+// ErrSignCount means an authenticator reported a signature counter that
+// didn't increase, which suggests the credential has been cloned.
+var ErrSignCount = errors.New("webauthn: signature counter went backwards")
+
+// URLEncoded is binary data carried in JSON as unpadded base64url, which is
+// how the browser script sends and receives it.
+type URLEncoded []byte
+
+func (b URLEncoded) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *URLEncoded) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// RelyingParty is this site as WebAuthn sees it. ID is the host name
+// passkeys are bound to and Origin the scheme, host and port pages are
+// served from.
+type RelyingParty struct {
+	ID     string
+	Name   string
+	Origin string
+}
+
+// Credential is a registered passkey: what has to be stored to verify
+// sign-ins with it.
+type Credential struct {
+	ID        []byte
+	PublicKey []byte // COSE_Key
+	SignCount uint32
+}
+
+// NewChallenge returns a random challenge for one ceremony. It must be kept
+// server-side, e.g. in the session, until the response comes back.
+func NewChallenge() ([]byte, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// CredentialDescriptor names a credential in options.
+type CredentialDescriptor struct {
+	Type string     `json:"type"`
+	ID   URLEncoded `json:"id"`
+}
+
+func descriptors(ids [][]byte) []CredentialDescriptor {
+	d := make([]CredentialDescriptor, len(ids))
+	for i, id := range ids {
+		d[i] = CredentialDescriptor{Type: "public-key", ID: id}
+	}
+	return d
+}
+
+// CredentialParameter is a key type the site accepts, in order of
+// preference.
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// CreationOptions are the publicKey options for navigator.credentials.create.
+type CreationOptions struct {
+	Challenge URLEncoded `json:"challenge"`
+	RP        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          URLEncoded `json:"id"`
+		Name        string     `json:"name"`
+		DisplayName string     `json:"displayName"`
+	} `json:"user"`
+	PubKeyCredParams       []CredentialParameter `json:"pubKeyCredParams"`
+	Timeout                int                   `json:"timeout"`
+	Attestation            string                `json:"attestation"`
+	AuthenticatorSelection struct {
+		ResidentKey        string `json:"residentKey"`
+		RequireResidentKey bool   `json:"requireResidentKey"`
+		UserVerification   string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+	ExcludeCredentials []CredentialDescriptor `json:"excludeCredentials"`
+}
+
+// CreationOptions returns the options for registering a passkey for the
+// user with handle userID. The passkey is discoverable, so it can sign in
+// without an email being typed first. exclude lists the user's existing
+// credentials so the same authenticator isn't registered twice.
+func (rp RelyingParty) CreationOptions(challenge, userID []byte, name, displayName string, exclude [][]byte) CreationOptions {
+	var o CreationOptions
+	o.Challenge = challenge
+	o.RP.ID = rp.ID
+	o.RP.Name = rp.Name
+	o.User.ID = userID
+	o.User.Name = name
+	o.User.DisplayName = displayName
+	o.PubKeyCredParams = []CredentialParameter{
+		{Type: "public-key", Alg: AlgES256},
+		{Type: "public-key", Alg: AlgRS256},
+	}
+	o.Timeout = Timeout
+	o.Attestation = "none"
+	o.AuthenticatorSelection.ResidentKey = "required"
+	o.AuthenticatorSelection.RequireResidentKey = true
+	o.AuthenticatorSelection.UserVerification = "required"
+	o.ExcludeCredentials = descriptors(exclude)
+	return o
+}
+
+// RequestOptions are the publicKey options for navigator.credentials.get.
+type RequestOptions struct {
+	Challenge        URLEncoded             `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int                    `json:"timeout"`
+	UserVerification string                 `json:"userVerification"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+}
+
+// RequestOptions returns the options for signing in. No credentials are
+// listed, so the browser offers whichever passkeys it has for this site.
+func (rp RelyingParty) RequestOptions(challenge []byte) RequestOptions {
+	return RequestOptions{
+		Challenge:        challenge,
+		RPID:             rp.ID,
+		Timeout:          Timeout,
+		UserVerification: "required",
+		AllowCredentials: []CredentialDescriptor{},
+	}
+}
+
+// RegistrationResponse is the credential returned by
+// navigator.credentials.create, as posted by the browser script.
+type RegistrationResponse struct {
+	ID       URLEncoded `json:"rawId"`
+	Response struct {
+		ClientDataJSON    URLEncoded `json:"clientDataJSON"`
+		AttestationObject URLEncoded `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the credential returned by navigator.credentials.get,
+// as posted by the browser script.
+type AssertionResponse struct {
+	ID       URLEncoded `json:"rawId"`
+	Response struct {
+		ClientDataJSON    URLEncoded `json:"clientDataJSON"`
+		AuthenticatorData URLEncoded `json:"authenticatorData"`
+		Signature         URLEncoded `json:"signature"`
+		UserHandle        URLEncoded `json:"userHandle"`
+	} `json:"response"`
+}
+
+// VerifyRegistration checks a registration response against the challenge
+// it was created for and returns the new credential.
+func (rp RelyingParty) VerifyRegistration(challenge []byte, resp RegistrationResponse) (Credential, error) {
+	if err := rp.verifyClientData(resp.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return Credential{}, err
+	}
+
+	v, _, err := decodeCBOR(resp.Response.AttestationObject)
+	if err != nil {
+		return Credential{}, fmt.Errorf("webauthn: attestation object: %w", err)
+	}
+	att, ok := v.(map[any]any)
+	if !ok {
+		return Credential{}, errors.New("webauthn: attestation object is not a map")
+	}
+	// Only "none" is requested, and browsers strip attestation to match, so
+	// there is no statement to check.
+	if format, _ := att["fmt"].(string); format != "none" {
+		return Credential{}, fmt.Errorf("webauthn: unsupported attestation format %q", format)
+	}
+	authData, ok := att["authData"].([]byte)
+	if !ok {
+		return Credential{}, errors.New("webauthn: attestation object has no authenticator data")
+	}
+
+	ad, err := rp.parseAuthData(authData)
+	if err != nil {
+		return Credential{}, err
+	}
+	if ad.flags&flagAttestedCredData == 0 {
+		return Credential{}, errors.New("webauthn: no credential in authenticator data")
+	}
+	if !bytes.Equal(ad.credentialID, resp.ID) {
+		return Credential{}, errors.New("webauthn: credential ID doesn't match authenticator data")
+	}
+	if _, err := parsePublicKey(ad.publicKey); err != nil {
+		return Credential{}, fmt.Errorf("webauthn: %w", err)
+	}
+
+	return Credential{ID: ad.credentialID, PublicKey: ad.publicKey, SignCount: ad.signCount}, nil
+}
+
+// VerifyAssertion checks a sign-in response for cred against the challenge
+// it was created for, and returns the credential's new signature counter to
+// store. It returns ErrSignCount if the counter suggests a cloned
+// authenticator.
+func (rp RelyingParty) VerifyAssertion(challenge []byte, cred Credential, resp AssertionResponse) (uint32, error) {
+	if !bytes.Equal(cred.ID, resp.ID) {
+		return 0, errors.New("webauthn: response is for a different credential")
+	}
+	if err := rp.verifyClientData(resp.Response.ClientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+
+	ad, err := rp.parseAuthData(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := parsePublicKey(cred.PublicKey)
+	if err != nil {
+		return 0, fmt.Errorf("webauthn: %w", err)
+	}
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signed := append(append([]byte(nil), resp.Response.AuthenticatorData...), clientDataHash[:]...)
+	if err := key.verify(signed, resp.Response.Signature); err != nil {
+		return 0, fmt.Errorf("webauthn: %w", err)
+	}
+
+	// Authenticators that don't keep a counter always report zero.
+	if (ad.signCount != 0 || cred.SignCount != 0) && ad.signCount <= cred.SignCount {
+		return 0, ErrSignCount
+	}
+	return ad.signCount, nil
+}
+
+// verifyClientData checks the client data's type, challenge and origin.
+func (rp RelyingParty) verifyClientData(raw []byte, typ string, challenge []byte) error {
+	var cd struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+		Origin    string `json:"origin"`
+	}
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return fmt.Errorf("webauthn: client data: %w", err)
+	}
+	if cd.Type != typ {
+		return fmt.Errorf("webauthn: expected %s, got %q", typ, cd.Type)
+	}
+	got, err := base64.RawURLEncoding.DecodeString(cd.Challenge)
+	if err != nil || len(challenge) == 0 || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return errors.New("webauthn: challenge doesn't match")
+	}
+	if cd.Origin != rp.Origin {
+		return fmt.Errorf("webauthn: unexpected origin %q", cd.Origin)
+	}
+	return nil
+}
+
+// authData is parsed authenticator data.
+type authData struct {
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+// parseAuthData parses authenticator data and checks it is for this site
+// and that the user was present and verified.
+func (rp RelyingParty) parseAuthData(data []byte) (authData, error) {
+	if len(data) < 37 {
+		return authData{}, errors.New("webauthn: authenticator data too short")
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(data[:32], rpIDHash[:]) != 1 {
+		return authData{}, errors.New("webauthn: authenticator data is for another site")
+	}
+
+	ad := authData{flags: data[32], signCount: binary.BigEndian.Uint32(data[33:37])}
+	if ad.flags&flagUserPresent == 0 {
+		return authData{}, errors.New("webauthn: user not present")
+	}
+	if ad.flags&flagUserVerified == 0 {
+		return authData{}, errors.New("webauthn: user not verified")
+	}
+
+	if ad.flags&flagAttestedCredData != 0 {
+		// AAGUID (16 bytes), credential ID length (2), credential ID, then
+		// the COSE public key, possibly followed by extensions.
+		rest := data[37:]
+		if len(rest) < 18 {
+			return authData{}, errors.New("webauthn: attested credential data too short")
+		}
+		n := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < n {
+			return authData{}, errors.New("webauthn: credential ID truncated")
+		}
+		ad.credentialID = append([]byte(nil), rest[:n]...)
+		rest = rest[n:]
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return authData{}, fmt.Errorf("webauthn: public key: %w", err)
+		}
+		ad.publicKey = append([]byte(nil), rest[:len(rest)-len(after)]...)
+	}
+	return ad, nil
+}
@@ -0,0 +1,159 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+var testRP = RelyingParty{ID: "example.com", Name: "Example", Origin: "https://example.com"}
+
+func challenge(t *testing.T) []byte {
+	t.Helper()
+	c, err := NewChallenge()
+	if err != nil {
+		t.Fatalf("Error creating challenge: %v", err)
+	}
+	return c
+}
+
+func TestRegisterAndSignIn(t *testing.T) {
+	for name, alg := range map[string]int{"ES256": AlgES256, "RS256": AlgRS256} {
+		t.Run(name, func(t *testing.T) {
+			auth := newAuthenticator(t, testRP, alg)
+
+			c := challenge(t)
+			cred, err := testRP.VerifyRegistration(c, auth.create(c))
+			if err != nil {
+				t.Fatalf("expected registration to succeed, got %v", err)
+			}
+			if string(cred.ID) != string(auth.id) {
+				t.Errorf("expected credential ID %q, got %q", auth.id, cred.ID)
+			}
+
+			for range 2 {
+				c = challenge(t)
+				count, err := testRP.VerifyAssertion(c, cred, auth.get(c))
+				if err != nil {
+					t.Fatalf("expected sign-in to succeed, got %v", err)
+				}
+				if count != auth.signCount {
+					t.Errorf("expected sign count %d, got %d", auth.signCount, count)
+				}
+				cred.SignCount = count
+			}
+		})
+	}
+}
+
+func TestVerifyRegistration_Rejects(t *testing.T) {
+	auth := newAuthenticator(t, testRP, AlgES256)
+	c := challenge(t)
+
+	otherSite := newAuthenticator(t, RelyingParty{ID: "evil.example", Origin: "https://example.com"}, AlgES256)
+	phished := newAuthenticator(t, RelyingParty{ID: "example.com", Origin: "https://examp1e.com"}, AlgES256)
+	unverified := newAuthenticator(t, testRP, AlgES256)
+	unverified.flags = flagUserPresent
+
+	tests := []struct {
+		name string
+		resp RegistrationResponse
+	}{
+		{"wrong challenge", auth.create(challenge(t))},
+		{"wrong origin", phished.create(c)},
+		{"wrong relying party", otherSite.create(c)},
+		{"user not verified", unverified.create(c)},
+		{"sign-in response", func() RegistrationResponse {
+			r := auth.create(c)
+			r.Response.ClientDataJSON = auth.clientData("webauthn.get", c)
+			return r
+		}()},
+		{"attestation format", func() RegistrationResponse {
+			r := auth.create(c)
+			r.Response.AttestationObject = encodeCBOR([]pair{
+				{"fmt", "packed"}, {"attStmt", []pair{}}, {"authData", auth.authData(true)},
+			})
+			return r
+		}()},
+		{"truncated", func() RegistrationResponse {
+			r := auth.create(c)
+			r.Response.AttestationObject = r.Response.AttestationObject[:60]
+			return r
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := testRP.VerifyRegistration(c, tt.resp); err == nil {
+				t.Error("expected registration to be rejected")
+			}
+		})
+	}
+}
+
+func TestVerifyAssertion_Rejects(t *testing.T) {
+	auth := newAuthenticator(t, testRP, AlgES256)
+	c := challenge(t)
+	cred, err := testRP.VerifyRegistration(c, auth.create(c))
+	if err != nil {
+		t.Fatalf("Error registering: %v", err)
+	}
+
+	other := newAuthenticator(t, testRP, AlgES256)
+	tests := []struct {
+		name string
+		resp AssertionResponse
+	}{
+		{"wrong challenge", auth.get(challenge(t))},
+		{"signed by another key", func() AssertionResponse {
+			r := other.get(c)
+			r.ID = auth.id
+			return r
+		}()},
+		{"tampered data", func() AssertionResponse {
+			r := auth.get(c)
+			r.Response.AuthenticatorData[33] ^= 0xff
+			return r
+		}()},
+		{"registration response", func() AssertionResponse {
+			r := auth.get(c)
+			r.Response.ClientDataJSON = auth.clientData("webauthn.create", c)
+			return r
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := testRP.VerifyAssertion(c, cred, tt.resp); err == nil {
+				t.Error("expected sign-in to be rejected")
+			}
+		})
+	}
+
+	t.Run("counter went backwards", func(t *testing.T) {
+		stored := cred
+		stored.SignCount = auth.signCount + 10
+		if _, err := testRP.VerifyAssertion(c, stored, auth.get(c)); !errors.Is(err, ErrSignCount) {
+			t.Errorf("expected ErrSignCount, got %v", err)
+		}
+	})
+}
+
+func TestOptionsJSON(t *testing.T) {
+	b, err := json.Marshal(testRP.CreationOptions([]byte{1, 2, 3}, []byte("42"), "me@example.com", "Me", [][]byte{{0xff}}))
+	if err != nil {
+		t.Fatalf("Error encoding options: %v", err)
+	}
+
+	var o struct {
+		Challenge string
+		User      struct{ ID string }
+		Exclude   []struct{ ID string } `json:"excludeCredentials"`
+	}
+	if err := json.Unmarshal(b, &o); err != nil {
+		t.Fatalf("Error decoding options: %v", err)
+	}
+	if o.Challenge != "AQID" || o.User.ID != "NDI" || len(o.Exclude) != 1 || o.Exclude[0].ID != "_w" {
+		t.Errorf("expected binary fields as base64url, got %s", b)
+	}
+}